@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListUsers returns a page of users, zero options use the server defaults.
+func (c *Client) ListUsers(ctx context.Context, opts ListUsersOptions) (UserList, error) {
+	query := url.Values{}
+	if opts.Query != "" {
+		query.Set("q", opts.Query)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var list UserList
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/admin/users",
+		query:  query,
+		auth:   true,
+		out:    &list,
+	})
+
+	return list, err
+}
+
+func (c *Client) GetUser(ctx context.Context, userID int) (UserWithEntries, error) {
+	var usr UserWithEntries
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/api/admin/users/%d", userID),
+		auth:   true,
+		out:    &usr,
+	})
+
+	return usr, err
+}
+
+// DeleteUser deletes the user and their entries.
+func (c *Client) DeleteUser(ctx context.Context, userID int) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/api/admin/users/%d", userID),
+		auth:   true,
+	})
+}
+
+func (c *Client) GrantAdmin(ctx context.Context, userID int) (User, error) {
+	return c.userAction(ctx, http.MethodPut, fmt.Sprintf("/api/admin/users/%d/admin", userID))
+}
+
+func (c *Client) RevokeAdmin(ctx context.Context, userID int) (User, error) {
+	return c.userAction(ctx, http.MethodDelete, fmt.Sprintf("/api/admin/users/%d/admin", userID))
+}
+
+func (c *Client) DeactivateUser(ctx context.Context, userID int) (User, error) {
+	return c.userAction(ctx, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/deactivate", userID))
+}
+
+func (c *Client) ReactivateUser(ctx context.Context, userID int) (User, error) {
+	return c.userAction(ctx, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/reactivate", userID))
+}
+
+func (c *Client) userAction(ctx context.Context, method, path string) (User, error) {
+	var usr User
+
+	err := c.do(ctx, request{
+		method: method,
+		path:   path,
+		auth:   true,
+		out:    &usr,
+	})
+
+	return usr, err
+}
@@ -0,0 +1,303 @@
+// Package client is a Go client of the practice-backend HTTP API. Its
+// methods are named after the operationId of internal/http/openapi.json,
+// a test fails when an operation has no method.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries    = 3
+	defaultBaseBackoff   = 100 * time.Millisecond
+	defaultMaxBackoff    = 2 * time.Second
+	defaultRefreshBefore = 5 * time.Minute
+)
+
+var ErrNoToken = errors.New("client has no token, log in first")
+
+// Client calls the API. It keeps the token of the last Login and sends
+// it with every request. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	maxRetries    int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+	refreshBefore time.Duration
+
+	mtx         *sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken starts the client with a token obtained earlier.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.setToken(token)
+	}
+}
+
+// WithRetries sets how many times a request failed with 5xx or a network
+// error is repeated, and the first delay, which doubles with every retry.
+func WithRetries(maxRetries int, baseBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseBackoff = baseBackoff
+	}
+}
+
+// WithRefreshBefore sets how long before its expiry the token is
+// refreshed, 0 turns the refresh off.
+func WithRefreshBefore(d time.Duration) Option {
+	return func(c *Client) {
+		c.refreshBefore = d
+	}
+}
+
+// New creates a client of the API at baseURL, e.g. "http://localhost:9091".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		httpClient:    http.DefaultClient,
+		maxRetries:    defaultMaxRetries,
+		baseBackoff:   defaultBaseBackoff,
+		maxBackoff:    defaultMaxBackoff,
+		refreshBefore: defaultRefreshBefore,
+		mtx:           new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Token returns the current token.
+func (c *Client) Token() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.token
+}
+
+func (c *Client) setToken(token string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.token = token
+	c.tokenExpiry = tokenExpiry(token)
+}
+
+// tokenExpiry reads the exp claim without checking the signature, only
+// the server can do that. A zero time means the expiry is unknown.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
+
+// authToken returns the token for a request, refreshing it first when it
+// is about to expire and refresh is set.
+func (c *Client) authToken(ctx context.Context, refresh bool) (string, error) {
+	c.mtx.Lock()
+	token, expiry := c.token, c.tokenExpiry
+	c.mtx.Unlock()
+
+	if token == "" {
+		return "", ErrNoToken
+	}
+
+	if refresh && c.refreshBefore > 0 && !expiry.IsZero() && time.Until(expiry) < c.refreshBefore {
+		if refreshed, err := c.RefreshToken(ctx); err == nil {
+			return refreshed, nil
+		}
+	}
+
+	return token, nil
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+	auth   bool
+	// noRefresh keeps the token as is, for the refresh request itself
+	noRefresh bool
+	// idempotent sends an Idempotency-Key, so the POST can be retried
+	idempotent bool
+	// out receives the JSON response body, or the raw body when it is a
+	// *[]byte, nil to ignore it
+	out any
+}
+
+// do sends the request. Idempotent requests are retried with exponential
+// backoff on 5xx and network errors, requests with an idempotency key also
+// while the server still serves the first attempt.
+func (c *Client) do(ctx context.Context, req request) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+	}
+
+	var token string
+	if req.auth {
+		var err error
+		if token, err = c.authToken(ctx, !req.noRefresh); err != nil {
+			return err
+		}
+	}
+
+	var idempotencyKey string
+	if req.idempotent {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	retries := 0
+	if isIdempotent(req.method) || req.idempotent {
+		retries = c.maxRetries
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		retryable, err = c.send(ctx, req, body, token, idempotencyKey)
+		if err == nil || !retryable || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(c.backoff(attempt)):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte, token, idempotencyKey string) (retryable bool, err error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, bodyReader)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	if idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		err := decodeError(resp)
+		return resp.StatusCode >= http.StatusInternalServerError || errors.Is(err, ErrIdempotencyInProgress), err
+	}
+
+	if req.out == nil || resp.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+
+	if raw, ok := req.out.(*[]byte); ok {
+		*raw, err = io.ReadAll(resp.Body)
+		return false, err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(req.out); err != nil {
+		return false, fmt.Errorf("decode %s %s response: %w", req.method, req.path, err)
+	}
+
+	return false, nil
+}
+
+// backoff is the delay before the retry after attempt, with jitter so that
+// clients don't retry in step.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.baseBackoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+
+	return d/2 + rand.N(d/2+1)
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	cryptorand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,679 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"practice-backend/client"
+	"practice-backend/internal/events"
+	httpServer "practice-backend/internal/http"
+	"practice-backend/internal/lib/jwt"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/webhooks"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *inmem.Storage) {
+	t.Helper()
+
+	storage := inmem.NewStorage()
+	authService := auth.NewAuth(storage, nil)
+	require.NoError(t, authService.CreateAdminUser(t.Context(), "admin", "admin"))
+
+	handlers := httpServer.NewHTTPHandlers(storage, storage, authService)
+	server := httpServer.NewHTTPServer(*handlers, httpServer.ServerConfig{Webhooks: storage})
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	return ts, storage
+}
+
+func TestClient(t *testing.T) {
+	ts, _ := newServer(t)
+	ctx := t.Context()
+
+	c := client.New(ts.URL)
+
+	userID, err := c.Register(ctx, client.RegisterRequest{
+		Login:      "ivan",
+		Password:   "secret",
+		Name:       "Ivan",
+		Surname:    "Ivanov",
+		Patronymic: "Ivanovich",
+		Phone:      "8 (999) 123-45-67",
+		Email:      "ivan@example.com",
+	})
+	require.NoError(t, err)
+
+	_, err = c.GetMe(ctx)
+	assert.ErrorIs(t, err, client.ErrNoToken)
+
+	_, err = c.Login(ctx, "ivan", "wrong")
+	assert.ErrorIs(t, err, client.ErrInvalidCredentials)
+
+	_, err = c.Login(ctx, "ivan", "secret")
+	require.NoError(t, err)
+
+	me, err := c.GetMe(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "+79991234567", me.Phone)
+	assert.Empty(t, me.EmailOptOuts, "all emails are sent by default")
+
+	me, err = c.UpdateMe(ctx, client.UpdateProfileRequest{EmailOptOuts: &[]string{client.EmailStatusChanges}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{client.EmailStatusChanges}, me.EmailOptOuts)
+
+	_, err = c.UpdateMe(ctx, client.UpdateProfileRequest{EmailOptOuts: &[]string{"news"}})
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "INVALID", apiErr.Fields["email_opt_outs"].Code)
+
+	e, err := c.CreateEntry(ctx, client.CreateEntryRequest{
+		Course:        "Go",
+		Date:          "2025-10-05",
+		UserID:        userID,
+		PaymentMethod: "card",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, client.StatusNotProcessed, e.Status)
+
+	entries, err := c.ListEntries(ctx, client.ListEntriesOptions{UserID: client.Int(userID)})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = c.UpdateEntry(ctx, e.ID, client.StatusProcessed, e.Version)
+	assert.ErrorIs(t, err, client.ErrNotAdmin)
+
+	admin := client.New(ts.URL)
+	_, err = admin.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	updated, err := admin.UpdateEntry(ctx, e.ID, client.StatusProcessed, e.Version)
+	require.NoError(t, err)
+	assert.Equal(t, client.StatusProcessed, updated.Status)
+	assert.Equal(t, e.Version+1, updated.Version)
+
+	_, err = admin.UpdateEntry(ctx, e.ID, client.StatusRejected, e.Version)
+	assert.ErrorIs(t, err, client.ErrPreconditionFailed, "stale version")
+
+	usr, err := admin.GetUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "ivan", usr.Login)
+	assert.Len(t, usr.Entries, 1)
+
+	list, err := admin.ListUsers(ctx, client.ListUsersOptions{Query: "ivan"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+
+	_, err = admin.DeactivateUser(ctx, userID)
+	require.NoError(t, err)
+
+	_, err = c.Login(ctx, "ivan", "secret")
+	assert.ErrorIs(t, err, client.ErrUserDeactivated)
+}
+
+func TestClientErrors(t *testing.T) {
+	ts, _ := newServer(t)
+	ctx := t.Context()
+
+	c := client.New(ts.URL)
+
+	_, err := c.Register(ctx, client.RegisterRequest{Login: "admin", Phone: "1"})
+
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	assert.Equal(t, client.CodeValidationFailed, apiErr.Code)
+	assert.Equal(t, "INVALID", apiErr.Fields["phone"].Code)
+	assert.Equal(t, "REQUIRED", apiErr.Fields["password"].Code)
+	assert.NotEmpty(t, apiErr.RequestID)
+
+	_, err = c.Register(ctx, client.RegisterRequest{
+		Login:      "admin",
+		Password:   "secret",
+		Name:       "Ivan",
+		Surname:    "Ivanov",
+		Patronymic: "Ivanovich",
+		Phone:      "+79991234567",
+		Email:      "ivan@example.com",
+	})
+	assert.ErrorIs(t, err, client.ErrUserExists)
+}
+
+func TestClientDeactivatedToken(t *testing.T) {
+	ts, _ := newServer(t)
+	ctx := t.Context()
+
+	admin := client.New(ts.URL)
+	_, err := admin.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	c := client.New(ts.URL)
+	userID, err := c.Register(ctx, client.RegisterRequest{Login: "ivan", Password: "secret", Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich", Phone: "89991234567", Email: "ivan@example.com"})
+	require.NoError(t, err)
+	_, err = c.Login(ctx, "ivan", "secret")
+	require.NoError(t, err)
+
+	_, err = admin.DeactivateUser(ctx, userID)
+	require.NoError(t, err)
+
+	_, err = c.GetMe(ctx)
+	assert.ErrorIs(t, err, client.ErrUserDeactivated, "the token of a deactivated user stops working")
+	_, err = c.CreateEntry(ctx, client.CreateEntryRequest{Course: "Go", Date: "2025-10-05", UserID: userID, PaymentMethod: "card"})
+	assert.ErrorIs(t, err, client.ErrUserDeactivated)
+
+	_, err = admin.ReactivateUser(ctx, userID)
+	require.NoError(t, err)
+	_, err = c.GetMe(ctx)
+	assert.NoError(t, err, "the token works again after reactivation")
+
+	require.NoError(t, admin.DeleteUser(ctx, userID))
+	_, err = c.GetMe(ctx)
+	assert.ErrorIs(t, err, client.ErrInvalidToken, "the token of a deleted user stops working")
+}
+
+func TestClientDeleteAccount(t *testing.T) {
+	ts, storage := newServer(t)
+	ctx := t.Context()
+
+	admin := client.New(ts.URL)
+	_, err := admin.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	register := func(login string) (*client.Client, int) {
+		c := client.New(ts.URL)
+		userID, err := c.Register(ctx, client.RegisterRequest{Login: login, Password: "secret", Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich", Phone: "89991234567", Email: login + "@example.com"})
+		require.NoError(t, err)
+		_, err = c.Login(ctx, login, "secret")
+		require.NoError(t, err)
+		_, err = c.CreateEntry(ctx, client.CreateEntryRequest{Course: "Go", Date: "2025-10-05", UserID: userID, PaymentMethod: "card"})
+		require.NoError(t, err)
+		return c, userID
+	}
+	entriesOf := func(userID int) int {
+		entries, err := storage.GetEntries(ctx)
+		require.NoError(t, err)
+		n := 0
+		for _, e := range entries {
+			if e.UserID == userID {
+				n++
+			}
+		}
+		return n
+	}
+
+	ivan, ivanID := register("ivan")
+	assert.ErrorIs(t, ivan.DeleteMe(ctx, "wrong"), client.ErrInvalidCredentials)
+	assert.Equal(t, 1, entriesOf(ivanID), "entries are kept when the password is wrong")
+
+	require.NoError(t, ivan.DeleteMe(ctx, "secret"))
+	assert.Zero(t, entriesOf(ivanID))
+	_, err = storage.GetUserByID(ctx, ivanID)
+	assert.ErrorIs(t, err, inmem.ErrUserNotFound)
+
+	_, petrID := register("petr")
+	require.NoError(t, admin.DeleteUser(ctx, petrID))
+	assert.Zero(t, entriesOf(petrID))
+	_, err = storage.GetUserByID(ctx, petrID)
+	assert.ErrorIs(t, err, inmem.ErrUserNotFound)
+}
+
+func TestClientRefreshesToken(t *testing.T) {
+	ts, storage := newServer(t)
+
+	admin, err := storage.GetUserByLogin(t.Context(), "admin")
+	require.NoError(t, err)
+
+	token, err := jwt.NewToken(admin, time.Now(), time.Minute)
+	require.NoError(t, err)
+
+	c := client.New(ts.URL, client.WithToken(token))
+
+	_, err = c.GetMe(t.Context())
+	require.NoError(t, err)
+	assert.NotEqual(t, token, c.Token())
+}
+
+func TestClientRefreshSessionAge(t *testing.T) {
+	ts, storage := newServer(t)
+
+	admin, err := storage.GetUserByLogin(t.Context(), "admin")
+	require.NoError(t, err)
+
+	sessionEnd := func(authTime time.Time) time.Time {
+		return authTime.Add(7 * 24 * time.Hour)
+	}
+
+	testCases := []struct {
+		title    string
+		authTime time.Time
+		wantErr  error
+	}{
+		{title: "happy: fresh login", authTime: time.Now().Add(-time.Hour)},
+		{title: "happy: the last hour of the session", authTime: time.Now().Add(-7*24*time.Hour + time.Hour)},
+		{title: "sad: session too old", authTime: time.Now().Add(-8 * 24 * time.Hour), wantErr: client.ErrInvalidToken},
+	}
+
+	for _, tc := range testCases {
+		token, err := jwt.NewToken(admin, tc.authTime, time.Hour)
+		require.NoError(t, err, tc.title)
+
+		refreshed, err := client.New(ts.URL, client.WithToken(token)).RefreshToken(t.Context())
+		if tc.wantErr != nil {
+			assert.ErrorIs(t, err, tc.wantErr, tc.title)
+			continue
+		}
+		require.NoError(t, err, tc.title)
+
+		claims := gojwt.MapClaims{}
+		_, _, err = gojwt.NewParser().ParseUnverified(refreshed, claims)
+		require.NoError(t, err, tc.title)
+		exp, err := claims.GetExpirationTime()
+		require.NoError(t, err, tc.title)
+		assert.False(t, exp.After(sessionEnd(tc.authTime)), "the refreshed token ends with the session: "+tc.title)
+		assert.Equal(t, float64(tc.authTime.Unix()), claims["auth_time"], "the login time is kept: "+tc.title)
+	}
+}
+
+func TestClientEntryEvents(t *testing.T) {
+	storage := inmem.NewStorage()
+	authService := auth.NewAuth(storage, nil)
+	require.NoError(t, authService.CreateAdminUser(t.Context(), "admin", "admin"))
+
+	bus := events.NewBus(0)
+	handlers := httpServer.NewHTTPHandlers(storage, storage, authService)
+	server := httpServer.NewHTTPServer(*handlers, httpServer.ServerConfig{Events: bus})
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	defer bus.Close()
+
+	relay := events.NewRelay(storage, events.RelayConfig{Interval: 5 * time.Millisecond})
+	relay.Handle("bus", events.BusHandler(bus))
+	relayCtx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Go(func() { relay.Run(relayCtx) })
+	defer wg.Wait()
+	defer cancel()
+
+	ctx := t.Context()
+	admin := client.New(ts.URL)
+	_, err := admin.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	users := make(map[string]*client.Client)
+	entries := make(map[string]client.Entry)
+	for _, login := range []string{"ivan", "petr"} {
+		c := client.New(ts.URL)
+		userID, err := c.Register(ctx, client.RegisterRequest{
+			Login:      login,
+			Password:   "secret",
+			Name:       "Name",
+			Surname:    "Surname",
+			Patronymic: "Patronymic",
+			Phone:      "+79991234567",
+			Email:      login + "@example.com",
+		})
+		require.NoError(t, err)
+		_, err = c.Login(ctx, login, "secret")
+		require.NoError(t, err)
+
+		e, err := c.CreateEntry(ctx, client.CreateEntryRequest{
+			Course:        "Go",
+			Date:          "2025-10-05",
+			UserID:        userID,
+			PaymentMethod: "card",
+		})
+		require.NoError(t, err)
+
+		users[login], entries[login] = c, e
+	}
+
+	stream, err := users["ivan"].EntryEvents(ctx, 0)
+	require.NoError(t, err)
+
+	_, err = admin.UpdateEntry(ctx, entries["petr"].ID, client.StatusProcessed, client.AnyVersion)
+	require.NoError(t, err)
+	_, err = admin.UpdateEntry(ctx, entries["ivan"].ID, client.StatusRejected, client.AnyVersion)
+	require.NoError(t, err)
+
+	e, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, client.EventEntryStatusChanged, e.Type)
+
+	en, err := e.Entry()
+	require.NoError(t, err)
+	assert.Equal(t, entries["ivan"].ID, en.ID, "the event of another user's entry is skipped")
+	assert.Equal(t, client.StatusRejected, en.Status)
+	require.NoError(t, stream.Close())
+	lastID := stream.LastEventID()
+
+	_, err = admin.UpdateEntry(ctx, entries["ivan"].ID, client.StatusProcessed, client.AnyVersion)
+	require.NoError(t, err)
+
+	stream, err = users["ivan"].EntryEvents(ctx, lastID)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	e, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, lastID+1, e.ID, "missed event is resent on resume")
+
+	en, err = e.Entry()
+	require.NoError(t, err)
+	assert.Equal(t, client.StatusProcessed, en.Status)
+
+	_, err = client.New(ts.URL).EntryEvents(ctx, 0)
+	assert.ErrorIs(t, err, client.ErrNoToken)
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	var keys sync.Map
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			keys.Store(key, true)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"is_admin": true}`))
+	}))
+	defer ts.Close()
+
+	c := client.New(ts.URL, client.WithRetries(3, time.Millisecond))
+
+	isAdmin, err := c.UserIsAdmin(t.Context(), 1)
+	require.NoError(t, err)
+	assert.True(t, isAdmin)
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	_, err = c.Login(t.Context(), "ivan", "secret")
+	assert.ErrorIs(t, err, client.ErrInternal)
+	assert.Equal(t, int32(1), calls.Load(), "POST without idempotency key must not be retried")
+
+	calls.Store(0)
+	_, err = c.Register(t.Context(), client.RegisterRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load(), "POST with idempotency key is retried")
+
+	var keyCount int
+	keys.Range(func(key, value any) bool {
+		keyCount++
+		return true
+	})
+	assert.Equal(t, 1, keyCount, "retries send the same idempotency key")
+}
+
+func TestClientWebhooks(t *testing.T) {
+	storage := inmem.NewStorage()
+	authService := auth.NewAuth(storage, nil)
+	require.NoError(t, authService.CreateAdminUser(t.Context(), "admin", "admin"))
+
+	handlers := httpServer.NewHTTPHandlers(storage, storage, authService)
+	server := httpServer.NewHTTPServer(*handlers, httpServer.ServerConfig{Webhooks: storage})
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	var failing atomic.Bool
+	failing.Store(true)
+	bodies := make(chan []byte, 1)
+	secret := "0123456789abcdef"
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		sentAt, _ := strconv.ParseInt(r.Header.Get(webhooks.TimestampHeader), 10, 64)
+		if !webhooks.Verify(secret, time.Unix(sentAt, 0), body, r.Header.Get(webhooks.SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	dispatcher := webhooks.NewDispatcher(storage, webhooks.Config{
+		MaxAttempts:  1,
+		PollInterval: 10 * time.Millisecond,
+		Data:         httpServer.EventData,
+	})
+	relay := events.NewRelay(storage, events.RelayConfig{Interval: 5 * time.Millisecond})
+	relay.Handle("webhooks", dispatcher.Enqueue)
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Go(func() { dispatcher.Run(ctx) })
+	wg.Go(func() { relay.Run(ctx) })
+	defer wg.Wait()
+	defer cancel()
+
+	admin := client.New(ts.URL)
+	_, err := admin.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	_, err = admin.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "ftp://crm", Events: []string{"user.registered"}})
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, client.CodeValidationFailed, apiErr.Code)
+	assert.Equal(t, "INVALID", apiErr.Fields["url"].Code)
+	assert.Equal(t, "INVALID", apiErr.Fields["events"].Code, "user events are not sent to webhooks")
+
+	hook, err := admin.CreateWebhook(ctx, client.CreateWebhookRequest{
+		URL:    receiver.URL,
+		Events: []string{client.EventEntryCreated},
+		Secret: secret,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, secret, hook.Secret)
+
+	hooks, err := admin.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.Empty(t, hooks[0].Secret, "the secret is shown on creation only")
+
+	_, err = admin.CreateEntry(ctx, client.CreateEntryRequest{
+		Course:        "Go",
+		Date:          "2025-10-05",
+		UserID:        1,
+		PaymentMethod: "card",
+	})
+	require.NoError(t, err)
+
+	var dead client.DeliveryList
+	require.Eventually(t, func() bool {
+		dead, err = admin.ListDeadLetters(ctx, 0, 0)
+		return err == nil && dead.Total == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, dead.Deliveries[0].ResponseStatus)
+	assert.Equal(t, 1, dead.Deliveries[0].Attempts)
+
+	failing.Store(false)
+	retried, err := admin.RetryDelivery(ctx, hook.ID, dead.Deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, client.DeliveryPending, retried.Status)
+
+	select {
+	case body := <-bodies:
+		var payload struct {
+			ID   int          `json:"id"`
+			Type string       `json:"type"`
+			Data client.Entry `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.NotZero(t, payload.ID)
+		assert.Equal(t, client.EventEntryCreated, payload.Type)
+		assert.Equal(t, "Go", payload.Data.Course)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retried delivery was not received")
+	}
+
+	require.Eventually(t, func() bool {
+		list, err := admin.ListDeliveries(ctx, hook.ID, client.ListDeliveriesOptions{Status: client.DeliveryDelivered})
+		return err == nil && list.Total == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = admin.RetryDelivery(ctx, hook.ID+1, dead.Deliveries[0].ID)
+	assert.ErrorIs(t, err, client.ErrDeliveryNotFound)
+
+	require.NoError(t, admin.DeleteWebhook(ctx, hook.ID))
+	_, err = admin.GetWebhook(ctx, hook.ID)
+	assert.ErrorIs(t, err, client.ErrWebhookNotFound)
+}
+
+func TestClientCalendar(t *testing.T) {
+	storage := inmem.NewStorage()
+	authService := auth.NewAuth(storage, nil)
+	require.NoError(t, authService.CreateAdminUser(t.Context(), "admin", "admin"))
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	handlers := httpServer.NewHTTPHandlers(storage, storage, authService)
+	server := httpServer.NewHTTPServer(*handlers, httpServer.ServerConfig{
+		Calendar: httpServer.CalendarConfig{Location: moscow, StartTime: 9 * time.Hour, Duration: 90 * time.Minute},
+	})
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	ctx := t.Context()
+	c := client.New(ts.URL)
+	admin := client.New(ts.URL)
+	_, err = admin.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	userID, err := c.Register(ctx, client.RegisterRequest{Login: "ivan", Password: "secret", Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich", Phone: "89991234567", Email: "ivan@example.com"})
+	require.NoError(t, err)
+	_, err = c.Login(ctx, "ivan", "secret")
+	require.NoError(t, err)
+
+	_, err = c.GetCalendar(ctx)
+	assert.ErrorIs(t, err, client.ErrCalendarNotFound, "the feed is off by default")
+
+	cal, err := c.CreateCalendar(ctx)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(cal.URL, ts.URL+"/api/calendar/"), cal.URL)
+
+	got, err := c.GetCalendar(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, cal.URL, got.URL)
+
+	entry, err := c.CreateEntry(ctx, client.CreateEntryRequest{Course: "Go", Date: "2025-10-05", UserID: userID, PaymentMethod: "card"})
+	require.NoError(t, err)
+
+	feed := func(url string) (int, string) {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := feed(cal.URL)
+	require.Equal(t, http.StatusOK, status)
+	for _, line := range []string{
+		"X-WR-TIMEZONE:Europe/Moscow\r\n",
+		"UID:entry-" + strconv.Itoa(entry.ID) + "@practice-backend\r\n",
+		"SUMMARY:Go\r\n",
+		"DTSTART:20251005T060000Z\r\n",
+		"DTEND:20251005T073000Z\r\n",
+		"SEQUENCE:0\r\n",
+		"STATUS:TENTATIVE\r\n",
+	} {
+		assert.Contains(t, body, line)
+	}
+
+	_, err = admin.UpdateEntry(ctx, entry.ID, client.StatusRejected, entry.Version)
+	require.NoError(t, err)
+
+	ics, err := c.GetEntryCalendar(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Contains(t, string(ics), "SEQUENCE:1\r\n", "a change raises the sequence")
+	assert.Contains(t, string(ics), "STATUS:CANCELLED\r\n", "a rejected entry is cancelled")
+
+	_, body = feed(cal.URL)
+	assert.Contains(t, body, "STATUS:CANCELLED\r\n")
+
+	rotated, err := c.CreateCalendar(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, cal.URL, rotated.URL)
+	status, _ = feed(cal.URL)
+	assert.Equal(t, http.StatusNotFound, status, "the old URL stops working")
+
+	require.NoError(t, c.DeleteCalendar(ctx))
+	status, _ = feed(rotated.URL)
+	assert.Equal(t, http.StatusNotFound, status)
+	_, err = c.GetCalendar(ctx)
+	assert.ErrorIs(t, err, client.ErrCalendarNotFound)
+
+	other := client.New(ts.URL)
+	_, err = other.Register(ctx, client.RegisterRequest{Login: "petr", Password: "secret", Name: "Petr", Surname: "Petrov", Patronymic: "Petrovich", Phone: "89991234568", Email: "petr@example.com"})
+	require.NoError(t, err)
+	_, err = other.Login(ctx, "petr", "secret")
+	require.NoError(t, err)
+	_, err = other.GetEntryCalendar(ctx, entry.ID)
+	assert.ErrorIs(t, err, client.ErrAccessDenied)
+}
+
+// TestClientCoversOpenAPI fails when an operation of the API has no
+// method of the same name.
+func TestClientCoversOpenAPI(t *testing.T) {
+	skip := map[string]bool{
+		"liveness":   true,
+		"readiness":  true,
+		"openapi":    true,
+		"docs":       true,
+		"adminCheck": true,
+		// the WebSocket is for the admin dashboard, Go services use EntryEvents
+		"webSocket": true,
+		// the feed is for calendar apps, at the URL of GetCalendar
+		"getCalendarFeed": true,
+	}
+
+	b, err := os.ReadFile("../internal/http/openapi.json")
+	require.NoError(t, err)
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(b, &doc))
+
+	clientType := reflect.TypeFor[*client.Client]()
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			if skip[op.OperationID] {
+				continue
+			}
+
+			name := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+			_, ok := clientType.MethodByName(name)
+			assert.True(t, ok, "%s %s: client has no method %s", strings.ToUpper(method), path, name)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) CreateEntry(ctx context.Context, req CreateEntryRequest) (Entry, error) {
+	var e Entry
+
+	err := c.do(ctx, request{
+		method:     http.MethodPost,
+		path:       "/api/entry",
+		body:       req,
+		auth:       true,
+		idempotent: true,
+		out:        &e,
+	})
+
+	return e, err
+}
+
+func (c *Client) ListEntries(ctx context.Context, opts ListEntriesOptions) ([]Entry, error) {
+	query := url.Values{}
+	if opts.UserID != nil {
+		query.Set("user_id", strconv.Itoa(*opts.UserID))
+	}
+
+	var resp struct {
+		Entries []Entry `json:"entries"`
+	}
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/entry",
+		query:  query,
+		auth:   true,
+		out:    &resp,
+	})
+
+	return resp.Entries, err
+}
+
+func (c *Client) GetEntry(ctx context.Context, entryID int) (Entry, error) {
+	var e Entry
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/api/entry/%d", entryID),
+		auth:   true,
+		out:    &e,
+	})
+
+	return e, err
+}
+
+// GetEntryCalendar returns the entry as an iCalendar file with one event,
+// for calendar apps to import.
+func (c *Client) GetEntryCalendar(ctx context.Context, entryID int) ([]byte, error) {
+	var ics []byte
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/api/entry/%d/calendar.ics", entryID),
+		auth:   true,
+		out:    &ics,
+	})
+
+	return ics, err
+}
+
+// UpdateEntry sets the status of the entry, admin only. It fails with
+// ErrPreconditionFailed when the entry is no longer at version, pass
+// AnyVersion to update it anyway.
+func (c *Client) UpdateEntry(ctx context.Context, entryID int, status string, version int) (Entry, error) {
+	var e Entry
+
+	err := c.do(ctx, request{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/api/entry/%d", entryID),
+		header: ifMatch(version),
+		body:   map[string]string{"status": status},
+		auth:   true,
+		out:    &e,
+	})
+
+	return e, err
+}
+
+// DeleteEntry deletes the entry if it is still at version, see UpdateEntry.
+func (c *Client) DeleteEntry(ctx context.Context, entryID int, version int) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/api/entry/%d", entryID),
+		header: ifMatch(version),
+		auth:   true,
+	})
+}
+
+func ifMatch(version int) http.Header {
+	if version == AnyVersion {
+		return http.Header{"If-Match": {"*"}}
+	}
+
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
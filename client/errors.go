@@ -0,0 +1,119 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Codes of the server's error envelope.
+const (
+	CodeInvalidJSON           = "INVALID_JSON"
+	CodeValidationFailed      = "VALIDATION_FAILED"
+	CodeInvalidID             = "INVALID_ID"
+	CodeInvalidQuery          = "INVALID_QUERY"
+	CodeInvalidCredentials    = "INVALID_CREDENTIALS"
+	CodeInvalidToken          = "INVALID_TOKEN"
+	CodeUserDeactivated       = "USER_DEACTIVATED"
+	CodeAccessDenied          = "ACCESS_DENIED"
+	CodeNotAdmin              = "NOT_ADMIN"
+	CodeOwnAccount            = "OWN_ACCOUNT"
+	CodeUserExists            = "USER_EXISTS"
+	CodeUserNotFound          = "USER_NOT_FOUND"
+	CodeEntryNotFound         = "ENTRY_NOT_FOUND"
+	CodeWebhookNotFound       = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound      = "DELIVERY_NOT_FOUND"
+	CodeCalendarNotFound      = "CALENDAR_NOT_FOUND"
+	CodePreconditionFailed    = "PRECONDITION_FAILED"
+	CodePreconditionRequired  = "PRECONDITION_REQUIRED"
+	CodeRouteNotFound         = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed      = "METHOD_NOT_ALLOWED"
+	CodeCORSRejected          = "CORS_REJECTED"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeInvalidIdempotencyKey = "INVALID_IDEMPOTENCY_KEY"
+	CodeIdempotencyInProgress = "IDEMPOTENCY_IN_PROGRESS"
+	CodeIdempotencyKeyReused  = "IDEMPOTENCY_KEY_REUSED"
+	CodeInternal              = "INTERNAL"
+)
+
+// Errors to match with errors.Is, they compare the code only.
+var (
+	ErrInvalidJSON           = &Error{Code: CodeInvalidJSON}
+	ErrValidationFailed      = &Error{Code: CodeValidationFailed}
+	ErrInvalidID             = &Error{Code: CodeInvalidID}
+	ErrInvalidQuery          = &Error{Code: CodeInvalidQuery}
+	ErrInvalidCredentials    = &Error{Code: CodeInvalidCredentials}
+	ErrInvalidToken          = &Error{Code: CodeInvalidToken}
+	ErrUserDeactivated       = &Error{Code: CodeUserDeactivated}
+	ErrAccessDenied          = &Error{Code: CodeAccessDenied}
+	ErrNotAdmin              = &Error{Code: CodeNotAdmin}
+	ErrOwnAccount            = &Error{Code: CodeOwnAccount}
+	ErrUserExists            = &Error{Code: CodeUserExists}
+	ErrUserNotFound          = &Error{Code: CodeUserNotFound}
+	ErrEntryNotFound         = &Error{Code: CodeEntryNotFound}
+	ErrWebhookNotFound       = &Error{Code: CodeWebhookNotFound}
+	ErrDeliveryNotFound      = &Error{Code: CodeDeliveryNotFound}
+	ErrCalendarNotFound      = &Error{Code: CodeCalendarNotFound}
+	ErrPreconditionFailed    = &Error{Code: CodePreconditionFailed}
+	ErrPreconditionRequired  = &Error{Code: CodePreconditionRequired}
+	ErrRateLimited           = &Error{Code: CodeRateLimited}
+	ErrInvalidIdempotencyKey = &Error{Code: CodeInvalidIdempotencyKey}
+	ErrIdempotencyInProgress = &Error{Code: CodeIdempotencyInProgress}
+	ErrIdempotencyKeyReused  = &Error{Code: CodeIdempotencyKeyReused}
+	ErrInternal              = &Error{Code: CodeInternal}
+)
+
+// FieldError is a violation of a request field.
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error answered by the server.
+//
+//	if errors.Is(err, client.ErrUserExists) { ... }
+//
+//	var apiErr *client.Error
+//	if errors.As(err, &apiErr) { log(apiErr.Fields) }
+type Error struct {
+	StatusCode int                   `json:"-"`
+	Code       string                `json:"code"`
+	Message    string                `json:"message"`
+	Fields     map[string]FieldError `json:"fields,omitempty"`
+	RequestID  string                `json:"request_id,omitempty"`
+	Time       time.Time             `json:"time"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	for field, fe := range e.Fields {
+		msg += fmt.Sprintf("; %s: %s", field, fe.Message)
+	}
+
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// decodeError reads the error envelope. Responses without one, e.g. from
+// a proxy, get the code INTERNAL for 5xx and an empty code otherwise.
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err == nil && json.Unmarshal(b, apiErr) == nil && apiErr.Code != "" {
+		return apiErr
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		apiErr.Code = CodeInternal
+	}
+	apiErr.Message = http.StatusText(resp.StatusCode)
+
+	return apiErr
+}
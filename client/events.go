@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Types of stream and webhook events.
+const (
+	EventEntryCreated       = "entry.created"
+	EventEntryStatusChanged = "entry.status_changed"
+	// EventReset means events were missed, reload what the stream tracks
+	EventReset = "reset"
+)
+
+// Event is a message of an event stream.
+type Event struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+// Entry decodes the entry of an entry event.
+func (e Event) Entry() (Entry, error) {
+	var en Entry
+	err := json.Unmarshal(e.Data, &en)
+
+	return en, err
+}
+
+// EventStream reads Server-Sent Events. It is not safe for concurrent use.
+type EventStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	lastID uint64
+}
+
+// EntryEvents streams status changes of the caller's entries, or of all
+// entries for admins, after the event with lastEventID, 0 for new ones.
+// Reconnect with LastEventID of the old stream to resume.
+func (c *Client) EntryEvents(ctx context.Context, lastEventID uint64) (*EventStream, error) {
+	token, err := c.authToken(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/entry/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastEventID, 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	return &EventStream{
+		body:   resp.Body,
+		reader: bufio.NewReader(resp.Body),
+		lastID: lastEventID,
+	}, nil
+}
+
+// Next blocks until the next event. It returns io.EOF when the server
+// ends the stream, e.g. on shutdown.
+func (s *EventStream) Next() (Event, error) {
+	var e Event
+	var data []string
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return Event{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// an event ends with a blank line, comments and retry
+			// lines make blocks without data
+			if e.Type == "" && data == nil {
+				continue
+			}
+			e.Data = json.RawMessage(strings.Join(data, "\n"))
+			if e.ID > 0 {
+				s.lastID = e.ID
+			}
+			return e, nil
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			e.ID, _ = strconv.ParseUint(value, 10, 64)
+		case "event":
+			e.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// LastEventID is the ID to resume after.
+func (s *EventStream) LastEventID() uint64 {
+	return s.lastID
+}
+
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entry statuses.
+const (
+	StatusNotProcessed = "not processed"
+	StatusProcessed    = "processed"
+	StatusRejected     = "rejected"
+)
+
+// Kinds of emails a user can opt out of.
+const (
+	EmailStatusChanges = "status_changes"
+	EmailReminders     = "reminders"
+)
+
+type RegisterRequest struct {
+	Login      string `json:"login"`
+	Password   string `json:"password"`
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+	Phone      string `json:"phone"`
+	Email      string `json:"email"`
+}
+
+type User struct {
+	ID          int    `json:"id"`
+	Login       string `json:"login"`
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	IsAdmin     bool   `json:"is_admin"`
+	Deactivated bool   `json:"deactivated"`
+	Language    string `json:"language"`
+	// EmailOptOuts are the kinds of emails the user doesn't get, e.g.
+	// EmailStatusChanges
+	EmailOptOuts []string `json:"email_opt_outs"`
+}
+
+type UserWithEntries struct {
+	User
+	Entries []Entry `json:"entries"`
+}
+
+type UserList struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// UpdateProfileRequest changes only the set fields.
+type UpdateProfileRequest struct {
+	Name       *string `json:"name,omitempty"`
+	Surname    *string `json:"surname,omitempty"`
+	Patronymic *string `json:"patronymic,omitempty"`
+	Phone      *string `json:"phone,omitempty"`
+	Email      *string `json:"email,omitempty"`
+	Language   *string `json:"language,omitempty"`
+	// EmailOptOuts replace the kinds of emails the user doesn't get, an
+	// empty slice turns all of them on
+	EmailOptOuts *[]string `json:"email_opt_outs,omitempty"`
+}
+
+type CreateEntryRequest struct {
+	Course string `json:"course"`
+	// Date is in the "2006-01-02" format
+	Date          string `json:"date"`
+	UserID        int    `json:"user_id"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+// AnyVersion makes UpdateEntry and DeleteEntry skip the version check.
+const AnyVersion = 0
+
+type Entry struct {
+	ID            int    `json:"id"`
+	Course        string `json:"course"`
+	Date          string `json:"date"`
+	UserID        int    `json:"user_id"`
+	PaymentMethod string `json:"payment_method"`
+	Status        string `json:"status"`
+	// Version is the entry version for UpdateEntry and DeleteEntry
+	Version int `json:"version"`
+}
+
+type Calendar struct {
+	// URL of the calendar feed for calendar apps to subscribe to. It is
+	// secret: anyone with it sees the entries of the user.
+	URL string `json:"url"`
+}
+
+type ListEntriesOptions struct {
+	// UserID keeps only the entries of this user when set
+	UserID *int
+}
+
+type ListUsersOptions struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+type CreateWebhookRequest struct {
+	URL string `json:"url"`
+	// Events are event types like EventEntryStatusChanged
+	Events []string `json:"events"`
+	// Secret of 16+ characters signs the payloads, the server generates
+	// one when it is empty
+	Secret string `json:"secret,omitempty"`
+}
+
+type Webhook struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only returned by CreateWebhook
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type Delivery struct {
+	ID        int             `json:"id"`
+	WebhookID int             `json:"webhook_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// NextAttemptAt is set for pending deliveries only
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	LastError      string     `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type DeliveryList struct {
+	Deliveries []Delivery `json:"deliveries"`
+	Total      int        `json:"total"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+}
+
+type ListDeliveriesOptions struct {
+	// Status keeps only the deliveries with this status when set
+	Status string
+	Limit  int
+	Offset int
+}
+
+// String returns a pointer to s, for the optional fields of requests.
+func String(s string) *string {
+	return &s
+}
+
+// Int returns a pointer to i, for the optional fields of requests.
+func Int(i int) *int {
+	return &i
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Register creates a user and returns their ID.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+
+	err := c.do(ctx, request{
+		method:     http.MethodPost,
+		path:       "/api/user/register",
+		body:       req,
+		idempotent: true,
+		out:        &resp,
+	})
+
+	return resp.ID, err
+}
+
+// Login gets a token and keeps it for the next requests.
+func (c *Client) Login(ctx context.Context, login, password string) (string, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/user/login",
+		body: map[string]string{
+			"login":    login,
+			"password": password,
+		},
+		out: &resp,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c.setToken(resp.Token)
+	return resp.Token, nil
+}
+
+// RefreshToken exchanges the current token for a new one and keeps it.
+// Requests refresh the token on their own shortly before it expires.
+func (c *Client) RefreshToken(ctx context.Context) (string, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+
+	err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/api/user/token/refresh",
+		auth:      true,
+		noRefresh: true,
+		out:       &resp,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c.setToken(resp.Token)
+	return resp.Token, nil
+}
+
+func (c *Client) GetMe(ctx context.Context) (User, error) {
+	var usr User
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/user/me",
+		auth:   true,
+		out:    &usr,
+	})
+
+	return usr, err
+}
+
+func (c *Client) UpdateMe(ctx context.Context, req UpdateProfileRequest) (User, error) {
+	var usr User
+
+	err := c.do(ctx, request{
+		method: http.MethodPatch,
+		path:   "/api/user/me",
+		body:   req,
+		auth:   true,
+		out:    &usr,
+	})
+
+	return usr, err
+}
+
+// DeleteMe deletes the account and its entries. The token is dropped.
+func (c *Client) DeleteMe(ctx context.Context, password string) error {
+	err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/api/user/me",
+		body:   map[string]string{"password": password},
+		auth:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.setToken("")
+	return nil
+}
+
+func (c *Client) ChangePassword(ctx context.Context, currentPassword, newPassword string) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/user/me/password",
+		body: map[string]string{
+			"current_password": currentPassword,
+			"new_password":     newPassword,
+		},
+		auth: true,
+	})
+}
+
+func (c *Client) UserIsAdmin(ctx context.Context, userID int) (bool, error) {
+	var resp struct {
+		IsAdmin bool `json:"is_admin"`
+	}
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/api/user/%d", userID),
+		out:    &resp,
+	})
+
+	return resp.IsAdmin, err
+}
+
+// GetCalendar returns the secret URL of the calendar feed of the user,
+// ErrCalendarNotFound when the feed is off.
+func (c *Client) GetCalendar(ctx context.Context) (Calendar, error) {
+	return c.calendar(ctx, http.MethodGet)
+}
+
+// CreateCalendar turns the calendar feed on with a new secret URL, the old
+// URL stops working.
+func (c *Client) CreateCalendar(ctx context.Context) (Calendar, error) {
+	return c.calendar(ctx, http.MethodPost)
+}
+
+// DeleteCalendar turns the calendar feed off.
+func (c *Client) DeleteCalendar(ctx context.Context) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/api/user/me/calendar",
+		auth:   true,
+	})
+}
+
+func (c *Client) calendar(ctx context.Context, method string) (Calendar, error) {
+	var cal Calendar
+
+	err := c.do(ctx, request{
+		method: method,
+		path:   "/api/user/me/calendar",
+		auth:   true,
+		out:    &cal,
+	})
+
+	return cal, err
+}
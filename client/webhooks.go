@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListWebhooks returns the webhooks, without their secrets.
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var list WebhookList
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/admin/webhooks",
+		auth:   true,
+		out:    &list,
+	})
+
+	return list.Webhooks, err
+}
+
+// CreateWebhook subscribes a URL to events. The returned webhook has the
+// secret the payloads are signed with.
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (Webhook, error) {
+	var hook Webhook
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/admin/webhooks",
+		body:   req,
+		auth:   true,
+		out:    &hook,
+	})
+
+	return hook, err
+}
+
+func (c *Client) GetWebhook(ctx context.Context, webhookID int) (Webhook, error) {
+	var hook Webhook
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/api/admin/webhooks/%d", webhookID),
+		auth:   true,
+		out:    &hook,
+	})
+
+	return hook, err
+}
+
+// DeleteWebhook deletes the webhook and drops its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID int) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/api/admin/webhooks/%d", webhookID),
+		auth:   true,
+	})
+}
+
+// ListDeliveries returns a page of the delivery log of the webhook, newest
+// first.
+func (c *Client) ListDeliveries(ctx context.Context, webhookID int, opts ListDeliveriesOptions) (DeliveryList, error) {
+	query := deliveriesQuery(opts.Limit, opts.Offset)
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+
+	return c.deliveries(ctx, fmt.Sprintf("/api/admin/webhooks/%d/deliveries", webhookID), query)
+}
+
+// ListDeadLetters returns a page of the deliveries of all webhooks that
+// ran out of attempts, newest first.
+func (c *Client) ListDeadLetters(ctx context.Context, limit, offset int) (DeliveryList, error) {
+	return c.deliveries(ctx, "/api/admin/webhooks/dead-letters", deliveriesQuery(limit, offset))
+}
+
+// RetryDelivery sends the delivery again with a fresh set of attempts.
+func (c *Client) RetryDelivery(ctx context.Context, webhookID, deliveryID int) (Delivery, error) {
+	var delivery Delivery
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/api/admin/webhooks/%d/deliveries/%d/retry", webhookID, deliveryID),
+		auth:   true,
+		out:    &delivery,
+	})
+
+	return delivery, err
+}
+
+func (c *Client) deliveries(ctx context.Context, path string, query url.Values) (DeliveryList, error) {
+	var list DeliveryList
+
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   path,
+		query:  query,
+		auth:   true,
+		out:    &list,
+	})
+
+	return list, err
+}
+
+func deliveriesQuery(limit, offset int) url.Values {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	return query
+}
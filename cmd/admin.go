@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/validation"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var (
+	ErrLoginRequired    = errors.New("--login is required")
+	ErrDataFileRequired = errors.New("--data or DATA_FILE is required")
+	ErrPasswordIsEmpty  = errors.New("password is empty")
+	ErrPasswordMismatch = errors.New("passwords do not match")
+)
+
+func runAdminCreate(args []string) error {
+	fs := flag.NewFlagSet("admin create", flag.ExitOnError)
+	login := fs.String("login", "", "login of the new admin")
+	dataFile := dataFileFlag(fs)
+	fs.Parse(args)
+
+	if *login == "" {
+		return ErrLoginRequired
+	}
+
+	return withDataFile(*dataFile, func(storage *inmem.Storage) error {
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		authService := auth.NewAuth(storage, nil)
+		if err := authService.CreateAdminUser(context.Background(), *login, password); err != nil {
+			return err
+		}
+
+		fmt.Printf("Admin %q created\n", *login)
+		return nil
+	})
+}
+
+func runAdminResetPassword(args []string) error {
+	fs := flag.NewFlagSet("admin reset-password", flag.ExitOnError)
+	login := fs.String("login", "", "login of the user")
+	dataFile := dataFileFlag(fs)
+	fs.Parse(args)
+
+	if *login == "" {
+		return ErrLoginRequired
+	}
+
+	return withDataFile(*dataFile, func(storage *inmem.Storage) error {
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		authService := auth.NewAuth(storage, nil)
+		if err := authService.ResetPassword(context.Background(), *login, password); err != nil {
+			return err
+		}
+
+		fmt.Printf("Password of %q reset\n", *login)
+		return nil
+	})
+}
+
+func dataFileFlag(fs *flag.FlagSet) *string {
+	return fs.String("data", os.Getenv("DATA_FILE"), "path to the data file (env DATA_FILE)")
+}
+
+func phoneRegionFlag(fs *flag.FlagSet) *string {
+	return fs.String("phone-region", envOr("PHONE_REGION", validation.DefaultRegion()), "region of phone numbers without the international prefix (env PHONE_REGION)")
+}
+
+func openStorage(dataFile string) (*inmem.Storage, error) {
+	storage := inmem.NewStorage()
+	if dataFile == "" {
+		return storage, nil
+	}
+
+	if err := storage.LoadFile(dataFile); err != nil {
+		return nil, fmt.Errorf("load %s: %w", dataFile, err)
+	}
+
+	return storage, nil
+}
+
+// withDataFile loads the data file, runs fn and saves the result back.
+// The server should be stopped meanwhile, otherwise it overwrites the file.
+func withDataFile(dataFile string, fn func(storage *inmem.Storage) error) error {
+	if dataFile == "" {
+		return ErrDataFileRequired
+	}
+
+	storage, err := openStorage(dataFile)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(storage); err != nil {
+		return err
+	}
+
+	return storage.SaveFile(dataFile)
+}
+
+// readPassword takes the password from ADMIN_PASSWORD, from stdin when
+// it is not a terminal, or asks for it twice on the terminal.
+func readPassword() (string, error) {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", ErrPasswordIsEmpty
+		}
+
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return "", ErrPasswordIsEmpty
+		}
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(password) == 0 {
+		return "", ErrPasswordIsEmpty
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	repeated, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(password) != string(repeated) {
+		return "", ErrPasswordMismatch
+	}
+
+	return string(password), nil
+}
@@ -1,12 +1,8 @@
 package main
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"practice-backend/internal/http"
-	"practice-backend/internal/services/auth"
-	"practice-backend/internal/storage/inmem"
+	"os"
 )
 
 var (
@@ -14,18 +10,63 @@ var (
 	ServerHost = "localhost"
 )
 
+const usage = `Usage: practice-backend <command> [flags]
+
+Commands:
+  serve                  start the HTTP server
+  admin create           create an admin user
+  admin reset-password   set a new password for a user
+  user list              print registered users
+  migrate                create or upgrade the data file
+
+Run "practice-backend <command> -h" for the command flags.
+
+Passwords are read from the ADMIN_PASSWORD environment variable, from stdin
+when it is not a terminal, or from an interactive prompt.
+`
+
 func main() {
-	storage := inmem.NewStorage()
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "ERR", err)
+		os.Exit(1)
+	}
+}
 
-	authService := auth.NewAuth(storage)
-	authService.CreateAdminUser(context.TODO(), "Admin1", "KorokNET")
+func run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("command is required")
+	}
 
-	handlers := http.NewHTTPHandlers(storage, storage, authService)
-	server := http.NewHTTPServer(*handlers, ServerPort, ServerHost)
+	cmd, args := args[0], args[1:]
 
-	log.Printf("Starting server %s:%d\n", "localhost", 9091)
+	switch cmd {
+	case "serve":
+		return runServe(args)
+	case "migrate":
+		return runMigrate(args)
+	case "admin", "user":
+		if len(args) == 0 {
+			fmt.Fprint(os.Stderr, usage)
+			return fmt.Errorf("%s: subcommand is required", cmd)
+		}
 
-	if err := server.Start(); err != nil {
-		fmt.Println("ERR", err)
+		switch sub := cmd + " " + args[0]; sub {
+		case "admin create":
+			return runAdminCreate(args[1:])
+		case "admin reset-password":
+			return runAdminResetPassword(args[1:])
+		case "user list":
+			return runUserList(args[1:])
+		default:
+			fmt.Fprint(os.Stderr, usage)
+			return fmt.Errorf("unknown command %q", sub)
+		}
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", cmd)
 	}
 }
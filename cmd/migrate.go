@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/validation"
+)
+
+// runMigrate creates the data file when it is missing and rewrites it
+// in the current snapshot format otherwise. Phone numbers are brought
+// to E.164.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dataFile := dataFileFlag(fs)
+	phoneRegion := phoneRegionFlag(fs)
+	fs.Parse(args)
+
+	if err := validation.SetDefaultRegion(*phoneRegion); err != nil {
+		return err
+	}
+
+	err := withDataFile(*dataFile, normalizePhones)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is at snapshot version %d\n", *dataFile, inmem.SnapshotVersion)
+	return nil
+}
+
+// normalizePhones stores phones of all users in E.164. Users without a
+// phone are skipped, numbers that can't be parsed are kept and reported.
+func normalizePhones(storage *inmem.Storage) error {
+	ctx := context.Background()
+
+	users, err := storage.GetUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if u.Phone == "" {
+			continue
+		}
+
+		phone, err := validation.NormalizePhone(u.Phone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "user %q: phone %q is kept: %v\n", u.Login, u.Phone, err)
+			continue
+		}
+		if phone == u.Phone {
+			continue
+		}
+
+		u.Phone = phone
+		if _, err := storage.UpdateUser(ctx, u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
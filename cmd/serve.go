@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"practice-backend/internal/events"
+	"practice-backend/internal/health"
+	"practice-backend/internal/http"
+	"practice-backend/internal/lib/logger"
+	"practice-backend/internal/lib/tracing"
+	"practice-backend/internal/mail"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/notify"
+	"practice-backend/internal/ratelimit"
+	"practice-backend/internal/reminders"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/storage/instrumented"
+	"practice-backend/internal/validation"
+	"practice-backend/internal/webhooks"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	host := fs.String("host", ServerHost, "host to listen on")
+	port := fs.Int("port", ServerPort, "port to listen on")
+	dataFile := dataFileFlag(fs)
+	phoneRegion := phoneRegionFlag(fs)
+	flushInterval := fs.Duration("flush-interval", 30*time.Second, "how often the data file is saved")
+	logFormat := fs.String("log-format", envOr("LOG_FORMAT", "text"), "log format, text or json (env LOG_FORMAT)")
+	logLevel := fs.String("log-level", envOr("LOG_LEVEL", "info"), "log level (env LOG_LEVEL)")
+	logRedact := fs.Bool("log-redact", true, "hide personal data in logs")
+	traceExporter := fs.String("trace-exporter", envOr("TRACE_EXPORTER", tracing.ExporterNone), "trace exporter: none, stdout, file or otlp (env TRACE_EXPORTER)")
+	traceFile := fs.String("trace-file", os.Getenv("TRACE_FILE"), "file for the file trace exporter (env TRACE_FILE)")
+	metricsAddr := fs.String("metrics-addr", envOr("METRICS_ADDR", "localhost:9090"), "host:port /metrics is served on, apart from the API; empty to not serve them (env METRICS_ADDR)")
+	shutdownDelay := fs.Duration("shutdown-delay", 0, "how long /readyz reports not ready before the server stops")
+	traceSampleRatio := fs.Float64("trace-sample-ratio", 1, "share of traces to record, from 0 to 1")
+	corsOrigins := fs.String("cors-origins", envOr("CORS_ORIGINS", "http://localhost:5173"), "comma-separated origins allowed to call the API, e.g. https://*.example.com (env CORS_ORIGINS)")
+	corsCredentials := fs.Bool("cors-credentials", false, "allow cross-origin requests with credentials")
+	corsMaxAge := fs.Duration("cors-max-age", 10*time.Minute, "how long browsers cache preflight answers")
+	rateLimitAPI := fs.String("rate-limit-api", envOr("RATE_LIMIT_API", "600/1m"), "requests per user or IP to the whole API, e.g. 600/1m, 0 is off (env RATE_LIMIT_API)")
+	rateLimitAuth := fs.String("rate-limit-auth", envOr("RATE_LIMIT_AUTH", "10/1m"), "register and login requests per IP (env RATE_LIMIT_AUTH)")
+	rateLimitEntries := fs.String("rate-limit-entries", envOr("RATE_LIMIT_ENTRIES", "30/1h"), "entries a user may create (env RATE_LIMIT_ENTRIES)")
+	idempotencyTTL := fs.Duration("idempotency-ttl", 24*time.Hour, "how long responses to requests with an Idempotency-Key are replayed")
+	eventBuffer := fs.Int("event-buffer", events.DefaultBufferSize, "how many events are kept for event streams to resume")
+	sseHeartbeat := fs.Duration("sse-heartbeat", http.DefaultSSEHeartbeat, "how often idle event streams get a heartbeat")
+	webhookMaxAttempts := fs.Int("webhook-max-attempts", webhooks.DefaultMaxAttempts, "attempts of a webhook delivery before it goes to the dead letters")
+	webhookBackoff := fs.Duration("webhook-backoff", webhooks.DefaultBackoff, "wait before the first retry of a webhook delivery, doubled with every attempt")
+	webhookTimeout := fs.Duration("webhook-timeout", webhooks.DefaultTimeout, "timeout of a webhook delivery request")
+	smtpAddr := fs.String("smtp-addr", os.Getenv("SMTP_ADDR"), "host:port of the SMTP server emails are sent through, password in env SMTP_PASSWORD (env SMTP_ADDR)")
+	smtpUsername := fs.String("smtp-username", os.Getenv("SMTP_USERNAME"), "SMTP user, empty to send without authentication (env SMTP_USERNAME)")
+	mailDir := fs.String("mail-dir", os.Getenv("MAIL_DIR"), "directory emails are written to instead of being sent, for development (env MAIL_DIR)")
+	mailFrom := fs.String("mail-from", envOr("MAIL_FROM", "Practice <noreply@localhost>"), "sender of the emails (env MAIL_FROM)")
+	reminderOffsets := fs.String("reminder-offsets", envOr("REMINDER_OFFSETS", "3d,2h"), "comma-separated times before the course start reminders are emailed at, e.g. 3d,2h (env REMINDER_OFFSETS)")
+	timezone := fs.String("timezone", envOr("TIMEZONE", "UTC"), "time zone the courses take place in, e.g. Europe/Moscow (env TIMEZONE)")
+	courseStart := fs.String("course-start", envOr("COURSE_START", "09:00"), "time of day the courses start at (env COURSE_START)")
+	courseDuration := fs.Duration("course-duration", http.DefaultCourseDuration, "how long a course takes in the calendars")
+	replicas := fs.Int("replicas", 1, "how many replicas of the server run; only one is supported while emails are sent, as the in-memory leases don't coordinate replicas")
+	fs.Parse(args)
+
+	cors := http.CORSConfig{
+		AllowedOrigins:   splitList(*corsOrigins),
+		AllowCredentials: *corsCredentials,
+		ExposedHeaders:   http.DefaultCORSExposedHeaders,
+		MaxAge:           *corsMaxAge,
+	}
+	if err := cors.Validate(); err != nil {
+		return err
+	}
+
+	rateLimits, err := parseRateLimits(*rateLimitAPI, *rateLimitAuth, *rateLimitEntries)
+	if err != nil {
+		return err
+	}
+
+	if *replicas < 1 {
+		return errors.New("--replicas: at least one replica runs")
+	}
+
+	offsets, err := parseOffsets(*reminderOffsets)
+	if err != nil {
+		return fmt.Errorf("--reminder-offsets: %w", err)
+	}
+	location, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return fmt.Errorf("--timezone: %w", err)
+	}
+	startTime, err := parseTimeOfDay(*courseStart)
+	if err != nil {
+		return fmt.Errorf("--course-start: %w", err)
+	}
+
+	log, err := logger.New(os.Stderr, logger.Config{
+		Format: *logFormat,
+		Level:  *logLevel,
+		Redact: *logRedact,
+	})
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(log)
+
+	if err := validation.SetDefaultRegion(*phoneRegion); err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    *traceExporter,
+		File:        *traceFile,
+		ServiceName: envOr("OTEL_SERVICE_NAME", "practice-backend"),
+		SampleRatio: *traceSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Error("flush traces", "error", err)
+		}
+	}()
+
+	storage, err := openStorage(*dataFile)
+	if err != nil {
+		return err
+	}
+
+	m := metrics.New()
+	bus := events.NewBus(*eventBuffer)
+	userRepo := instrumented.NewUserRepo(storage, m)
+	entryRepo := instrumented.NewEntryRepo(storage, m)
+
+	authService := auth.NewAuth(userRepo, m)
+	if err := bootstrapAdmin(context.Background(), authService); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hc := health.New(2 * time.Second)
+	hc.Register("storage", storage)
+
+	mailer, err := newMailer(*smtpAddr, *smtpUsername, *mailDir, *mailFrom)
+	if err != nil {
+		return err
+	}
+	if mailer != nil {
+		// every replica would hold its own in-memory lease and send the
+		// same reminders
+		if *replicas > 1 {
+			return errors.New("--replicas: the reminders can't be sent by more than one replica, the in-memory leases don't coordinate replicas")
+		}
+		hc.Register("mailer", mailer)
+	} else {
+		log.Warn("no SMTP server or mail directory set, emails are not sent")
+	}
+
+	// the writer outlives the server, so writes made while draining
+	// requests are saved by its last flush
+	writerCtx, stopWriter := context.WithCancel(context.Background())
+	defer stopWriter()
+
+	writerDone := make(chan error, 1)
+	if *dataFile != "" {
+		writer := inmem.NewFileWriter(storage, *dataFile, *flushInterval)
+		hc.Register("persistence", writer)
+		go func() { writerDone <- writer.Run(writerCtx) }()
+	} else {
+		log.Warn("no data file set, data is kept in memory only")
+		writerDone <- nil
+	}
+
+	// the relay and the dispatcher stop after the server, so events of
+	// drained requests are relayed, and before the writer, so their state
+	// is saved. Events not relayed yet stay in the outbox for the next run.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	dispatcher := webhooks.NewDispatcher(storage, webhooks.Config{
+		MaxAttempts: *webhookMaxAttempts,
+		Backoff:     *webhookBackoff,
+		Timeout:     *webhookTimeout,
+		Data:        http.EventData,
+		Logger:      log,
+		Metrics:     m,
+	})
+	dispatcherDone := make(chan error, 1)
+	go func() { dispatcherDone <- dispatcher.Run(workersCtx) }()
+
+	relay := events.NewRelay(storage, events.RelayConfig{Logger: log})
+	relay.Handle("bus", events.BusHandler(bus))
+	relay.Handle("webhooks", dispatcher.Enqueue)
+	if mailer != nil {
+		relay.Handle(notify.HandlerName, notify.NewNotifier(userRepo, entryRepo, storage, mailer, log).Handle)
+	}
+	relayDone := make(chan error, 1)
+	go func() { relayDone <- relay.Run(workersCtx) }()
+
+	// reminders are only worth recording when they can be emailed
+	schedulerDone := make(chan error, 1)
+	if mailer != nil {
+		scheduler := reminders.NewScheduler(entryRepo, storage, reminders.Config{
+			Offsets:   offsets,
+			Location:  location,
+			StartTime: startTime,
+			Logger:    log,
+		})
+		go func() { schedulerDone <- scheduler.Run(workersCtx) }()
+	} else {
+		schedulerDone <- nil
+	}
+
+	handlers := http.NewHTTPHandlers(entryRepo, userRepo, authService)
+	server := http.NewHTTPServer(*handlers, http.ServerConfig{
+		Host:           *host,
+		Port:           *port,
+		Logger:         log,
+		Metrics:        m,
+		MetricsAddr:    *metricsAddr,
+		Health:         hc,
+		CORS:           cors,
+		RateLimits:     rateLimits,
+		IdempotencyTTL: *idempotencyTTL,
+		Events:         bus,
+		SSEHeartbeat:   *sseHeartbeat,
+		Webhooks:       storage,
+		Calendar: http.CalendarConfig{
+			Location:  location,
+			StartTime: startTime,
+			Duration:  *courseDuration,
+		},
+	})
+
+	serverDone := make(chan error, 1)
+	go func() { serverDone <- server.Start() }()
+
+	log.Info("starting server", "host", *host, "port", *port, "metrics_addr", *metricsAddr)
+
+	select {
+	case err = <-serverDone:
+	case <-ctx.Done():
+		log.Info("shutting down", "delay", *shutdownDelay)
+
+		hc.SetShuttingDown()
+		time.Sleep(*shutdownDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err = server.Shutdown(shutdownCtx)
+	}
+
+	stopWorkers()
+	err = errors.Join(err, <-relayDone, <-dispatcherDone, <-schedulerDone)
+
+	stopWriter()
+	return errors.Join(err, <-writerDone)
+}
+
+// mailerWithHealth is a mailer the readiness probe checks.
+type mailerWithHealth interface {
+	mail.Mailer
+	health.HealthChecker
+}
+
+// newMailer sends through the SMTP server when addr is set, writes to dir
+// when it is set, and is nil when neither is.
+func newMailer(addr, username, dir, from string) (mailerWithHealth, error) {
+	switch {
+	case addr != "":
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Addr:     addr,
+			Username: username,
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+	case dir != "":
+		return mail.NewFileMailer(dir, from)
+	}
+
+	return nil, nil
+}
+
+// bootstrapAdmin creates the admin from ADMIN_LOGIN and ADMIN_PASSWORD when
+// both are set, so a memory-only server can still be administered.
+func bootstrapAdmin(ctx context.Context, authService *auth.Auth) error {
+	login, password := os.Getenv("ADMIN_LOGIN"), os.Getenv("ADMIN_PASSWORD")
+	if login == "" || password == "" {
+		return nil
+	}
+
+	err := authService.CreateAdminUser(ctx, login, password)
+	if errors.Is(err, inmem.ErrUserAlreadyExist) {
+		return nil
+	}
+
+	return err
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func envOr(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseRateLimits(api, auth, entries string) (http.RateLimits, error) {
+	var limits http.RateLimits
+	for _, l := range []struct {
+		flag  string
+		value string
+		limit *ratelimit.Limit
+	}{
+		{flag: "rate-limit-api", value: api, limit: &limits.API},
+		{flag: "rate-limit-auth", value: auth, limit: &limits.Auth},
+		{flag: "rate-limit-entries", value: entries, limit: &limits.Entries},
+	} {
+		limit, err := ratelimit.ParseLimit(l.value)
+		if err != nil {
+			return http.RateLimits{}, fmt.Errorf("--%s: %w", l.flag, err)
+		}
+		*l.limit = limit
+	}
+
+	return limits, nil
+}
+
+// parseOffsets parses comma-separated durations, which may also be given
+// in days, e.g. "3d,2h".
+func parseOffsets(s string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, item := range splitList(s) {
+		var (
+			d   time.Duration
+			err error
+		)
+		if days, ok := strings.CutSuffix(item, "d"); ok {
+			var n int
+			n, err = strconv.Atoi(days)
+			d = time.Duration(n) * 24 * time.Hour
+		} else {
+			d, err = time.ParseDuration(item)
+		}
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid offset %q", item)
+		}
+		offsets = append(offsets, d)
+	}
+
+	return offsets, nil
+}
+
+// parseTimeOfDay parses "15:04" into the time since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func runUserList(args []string) error {
+	fs := flag.NewFlagSet("user list", flag.ExitOnError)
+	dataFile := dataFileFlag(fs)
+	fs.Parse(args)
+
+	if *dataFile == "" {
+		return ErrDataFileRequired
+	}
+
+	storage, err := openStorage(*dataFile)
+	if err != nil {
+		return err
+	}
+
+	users, err := storage.GetUsers(context.Background())
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tLOGIN\tNAME\tEMAIL\tADMIN\tDEACTIVATED")
+	for _, u := range users {
+		name := u.Surname + " " + u.Name + " " + u.Patronymic
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%t\n", u.ID, u.Login, name, u.Email, u.IsAdmin, u.Deactivated)
+	}
+
+	return tw.Flush()
+}
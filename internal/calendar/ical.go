@@ -0,0 +1,142 @@
+// Package calendar writes iCalendar (RFC 5545) files that calendar apps
+// import or subscribe to.
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType of the iCalendar files.
+const ContentType = "text/calendar; charset=utf-8"
+
+// Statuses of an event. A cancelled event is removed from the calendars
+// that had it.
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const (
+	prodID = "-//practice-backend//calendar//EN"
+	// lineLimit is how long a content line may be in octets, the CRLF
+	// not counted
+	lineLimit = 75
+	// utcLayout writes times in UTC, which every app takes without a
+	// VTIMEZONE and shows in the local time of its user
+	utcLayout = "20060102T150405Z"
+)
+
+type Calendar struct {
+	// Name is shown by the apps subscribed to the calendar
+	Name string
+	// Timezone is the IANA name of the time zone the events take place
+	// in, a hint for the apps
+	Timezone string
+	// RefreshInterval is how often subscribed apps should fetch the
+	// calendar again, zero leaves it to them
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is a VEVENT. Apps match the versions of an event by UID and take
+// the one with the highest Sequence.
+type Event struct {
+	UID      string
+	Sequence int
+	// Stamp is when the event was written
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Status      string
+}
+
+// Encode returns the calendar as an iCalendar file with CRLF line endings.
+func (c Calendar) Encode() []byte {
+	var w writer
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", prodID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.Timezone != "" {
+		w.line("X-WR-TIMEZONE", c.Timezone)
+	}
+	if c.RefreshInterval > 0 {
+		w.line("REFRESH-INTERVAL;VALUE=DURATION", duration(c.RefreshInterval))
+		w.line("X-PUBLISHED-TTL", duration(c.RefreshInterval))
+	}
+
+	for _, e := range c.Events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", escape(e.UID))
+		w.line("SEQUENCE", fmt.Sprint(e.Sequence))
+		w.line("DTSTAMP", e.Stamp.UTC().Format(utcLayout))
+		w.line("DTSTART", e.Start.UTC().Format(utcLayout))
+		w.line("DTEND", e.End.UTC().Format(utcLayout))
+		w.line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Status != "" {
+			w.line("STATUS", e.Status)
+		}
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+
+	return w.buf.Bytes()
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folded into lines of up to lineLimit
+// octets without splitting a character.
+func (w *writer) line(name string, value string) {
+	line := name + ":" + value
+
+	limit := lineLimit
+	for len(line) > limit {
+		cut := limit
+		for !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.buf.WriteString(line[:cut])
+		// a folded line starts with a space, which counts to its length
+		w.buf.WriteString("\r\n ")
+		line = line[cut:]
+		limit = lineLimit - 1
+	}
+	w.buf.WriteString(line)
+	w.buf.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// duration writes d as a whole number of minutes, e.g. PT90M.
+func duration(d time.Duration) string {
+	return fmt.Sprintf("PT%dM", int(d.Minutes()))
+}
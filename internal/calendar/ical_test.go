@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	start := time.Date(2025, 10, 5, 9, 0, 0, 0, moscow)
+
+	c := Calendar{
+		Name:            "Courses",
+		Timezone:        "Europe/Moscow",
+		RefreshInterval: time.Hour,
+		Events: []Event{{
+			UID:         "entry-1@practice-backend",
+			Sequence:    2,
+			Stamp:       time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+			Start:       start,
+			End:         start.Add(90 * time.Minute),
+			Summary:     "Go; basics, part 1",
+			Description: "line one\nline two",
+			Status:      StatusCancelled,
+		}},
+	}
+
+	got := string(c.Encode())
+
+	for _, line := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Courses\r\n",
+		"X-WR-TIMEZONE:Europe/Moscow\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT60M\r\n",
+		"UID:entry-1@practice-backend\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTAMP:20251001T120000Z\r\n",
+		"DTSTART:20251005T060000Z\r\n",
+		"DTEND:20251005T073000Z\r\n",
+		`SUMMARY:Go\; basics\, part 1` + "\r\n",
+		`DESCRIPTION:line one\nline two` + "\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		assert.Contains(t, got, line)
+	}
+	assert.True(t, strings.HasSuffix(got, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+}
+
+func TestLineFolding(t *testing.T) {
+	testCases := []struct {
+		title   string
+		summary string
+	}{
+		{title: "happy: short", summary: "Go"},
+		{title: "happy: long ASCII", summary: strings.Repeat("a", 200)},
+		{title: "happy: long multibyte", summary: strings.Repeat("курс ", 40)},
+	}
+
+	for _, tc := range testCases {
+		got := string(Calendar{Events: []Event{{Summary: tc.summary}}}.Encode())
+
+		var summary strings.Builder
+		inSummary := false
+		for line := range strings.SplitSeq(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+			assert.LessOrEqual(t, len(line), lineLimit, tc.title)
+
+			switch {
+			case strings.HasPrefix(line, "SUMMARY:"):
+				inSummary = true
+				summary.WriteString(strings.TrimPrefix(line, "SUMMARY:"))
+			case inSummary && strings.HasPrefix(line, " "):
+				summary.WriteString(line[1:])
+			default:
+				inSummary = false
+			}
+		}
+		assert.Equal(t, tc.summary, summary.String(), tc.title)
+	}
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"practice-backend/internal/models/outbox"
+	"sync"
+	"time"
+)
+
+// Type names what happened, clients switch on it.
+type Type string
+
+const (
+	EntryCreated       Type = outbox.EntryCreated
+	EntryStatusChanged Type = outbox.EntryStatusChanged
+	EntryDeleted       Type = outbox.EntryDeleted
+	EntryReminder      Type = outbox.EntryReminder
+	UserRegistered     Type = outbox.UserRegistered
+	UserUpdated        Type = outbox.UserUpdated
+	UserDeleted        Type = outbox.UserDeleted
+)
+
+// Event is a change published on the Bus.
+type Event struct {
+	// ID grows with every published event, clients resume after it
+	ID   uint64
+	Type Type
+	Time time.Time
+	// UserID is the user the event concerns, e.g. the entry owner
+	UserID int
+	// Data is the changed object, e.g. entry.Entry
+	Data any
+	// MessageID is the ID of the outbox message of the event. Unlike ID it
+	// stays the same when the event is handed over again, e.g. after a
+	// restart, so consumers drop repeats by it.
+	MessageID int
+}
+
+const (
+	DefaultBufferSize       = 1024
+	subscriptionChannelSize = 64
+)
+
+// Bus fans published events out to subscribers and keeps the last ones in
+// a bounded buffer, so subscribers can resume after a reconnect.
+// Concurrent-Use
+type Bus struct {
+	mtx    *sync.Mutex
+	nextID uint64
+	// buffer is a ring of the last published events, oldest at start
+	buffer []Event
+	start  int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBus keeps up to bufferSize events for resuming, DefaultBufferSize
+// when it is not positive.
+func NewBus(bufferSize int) *Bus {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	return &Bus{
+		mtx:    new(sync.Mutex),
+		nextID: 1,
+		buffer: make([]Event, 0, bufferSize),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish gives the event an ID and a time and sends it to the
+// subscribers. It never blocks: a subscriber that doesn't keep up is
+// dropped and has to resume.
+func (b *Bus) Publish(e Event) Event {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	e.ID = b.nextID
+	b.nextID++
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	if len(b.buffer) < cap(b.buffer) {
+		b.buffer = append(b.buffer, e)
+	} else {
+		b.buffer[b.start] = e
+		b.start = (b.start + 1) % len(b.buffer)
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.c <- e:
+		default:
+			b.drop(sub)
+		}
+	}
+
+	return e
+}
+
+// Subscribe returns a subscription to events published after the event
+// with afterID, 0 for new events only. The buffered events after afterID
+// are returned as missed; complete is false when some of them are no
+// longer buffered.
+func (b *Bus) Subscribe(afterID uint64) (sub *Subscription, missed []Event, complete bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	sub = &Subscription{
+		bus: b,
+		c:   make(chan Event, subscriptionChannelSize),
+	}
+	if b.closed {
+		close(sub.c)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+
+	complete = true
+	if afterID >= b.nextID {
+		// the ID is from before a restart
+		complete = false
+	} else if afterID > 0 {
+		for i := range b.buffer {
+			e := b.buffer[(b.start+i)%len(b.buffer)]
+			if i == 0 && e.ID > afterID+1 {
+				complete = false
+			}
+			if e.ID > afterID {
+				missed = append(missed, e)
+			}
+		}
+		if len(b.buffer) == 0 && b.nextID > afterID+1 {
+			complete = false
+		}
+	}
+
+	return sub, missed, complete
+}
+
+// Close ends all subscriptions, e.g. on shutdown. Later subscriptions
+// end at once.
+func (b *Bus) Close() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+// drop ends the subscription. The caller must hold b.mtx.
+func (b *Bus) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.c)
+}
+
+// Subscription receives the events of a Bus until it is closed.
+type Subscription struct {
+	bus *Bus
+	c   chan Event
+}
+
+// C is closed when the subscription ends: on Close, when the bus is
+// closed or when the subscriber didn't keep up.
+func (s *Subscription) C() <-chan Event {
+	return s.c
+}
+
+func (s *Subscription) Close() {
+	s.bus.mtx.Lock()
+	defer s.bus.mtx.Unlock()
+
+	s.bus.drop(s)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusResume(t *testing.T) {
+	bus := NewBus(3)
+	for range 5 {
+		bus.Publish(Event{Type: EntryStatusChanged})
+	}
+
+	testCases := []struct {
+		title        string
+		afterID      uint64
+		wantIDs      []uint64
+		wantComplete bool
+	}{
+		{title: "happy: new events only", afterID: 0, wantComplete: true},
+		{title: "happy: resume from buffer", afterID: 3, wantIDs: []uint64{4, 5}, wantComplete: true},
+		{title: "happy: resume from the oldest buffered", afterID: 2, wantIDs: []uint64{3, 4, 5}, wantComplete: true},
+		{title: "sad: missed events are gone", afterID: 1, wantIDs: []uint64{3, 4, 5}, wantComplete: false},
+		{title: "sad: id from before a restart", afterID: 10, wantComplete: false},
+	}
+
+	for _, tc := range testCases {
+		sub, missed, complete := bus.Subscribe(tc.afterID)
+		sub.Close()
+
+		var ids []uint64
+		for _, e := range missed {
+			ids = append(ids, e.ID)
+		}
+
+		assert.Equal(t, tc.wantIDs, ids, tc.title)
+		assert.Equal(t, tc.wantComplete, complete, tc.title)
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	bus := NewBus(0)
+	slow, _, _ := bus.Subscribe(0)
+	fast, _, _ := bus.Subscribe(0)
+
+	for i := range subscriptionChannelSize + 1 {
+		bus.Publish(Event{Type: EntryStatusChanged, UserID: i})
+		<-fast.C()
+	}
+
+	var received int
+	for range slow.C() {
+		received++
+	}
+	assert.Equal(t, subscriptionChannelSize, received, "slow subscription is closed once its channel is full")
+
+	bus.Publish(Event{Type: EntryStatusChanged})
+	e, ok := <-fast.C()
+	require.True(t, ok)
+	assert.Equal(t, uint64(subscriptionChannelSize+2), e.ID)
+
+	bus.Close()
+	_, ok = <-fast.C()
+	assert.False(t, ok, "Close ends subscriptions")
+}
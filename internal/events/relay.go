@@ -0,0 +1,212 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"practice-backend/internal/models/outbox"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRelayInterval   = 50 * time.Millisecond
+	DefaultRelayRetryDelay = 5 * time.Second
+	// DefaultRelayMaxAttempts gives a failing handler about eight minutes
+	// at the default retry delay
+	DefaultRelayMaxAttempts = 100
+
+	// relayBatchSize is how many messages are read from the outbox at once
+	relayBatchSize = 100
+)
+
+// Handler takes an event of the relay. On error the relay hands the event
+// over again later, so a handler gets every event at least once and has
+// to put up with repeats.
+type Handler func(ctx context.Context, e Event) error
+
+// BusHandler publishes the events on the bus.
+func BusHandler(bus *Bus) Handler {
+	return func(ctx context.Context, e Event) error {
+		bus.Publish(e)
+		return nil
+	}
+}
+
+type RelayConfig struct {
+	// Interval is how often the outbox is looked at
+	Interval time.Duration
+	// RetryDelay is how long Run waits before a handler that failed gets
+	// the event again
+	RetryDelay time.Duration
+	// MaxAttempts is how often a handler gets a message before it is
+	// dropped for that handler as a dead letter, so one message the
+	// handler can never take doesn't stop it and grow the outbox forever
+	MaxAttempts int
+	Logger      *slog.Logger
+}
+
+type relayHandler struct {
+	name   string
+	handle Handler
+	// after is the ID of the last message the handler took. It starts
+	// at 0 on every run, so after a restart the handlers get the messages
+	// left in the outbox again.
+	after int
+	// retryAt is when a failed handler is tried again
+	retryAt time.Time
+	// attempts counts the failures on the message following after
+	attempts int
+}
+
+// Relay hands the messages of the outbox over to the handlers in the
+// order they were recorded and deletes them once every handler took
+// them. Every handler moves on its own: one that fails gets the message
+// again after a delay, and the others don't wait for it.
+// Concurrent-Use
+type Relay struct {
+	repo     outbox.OutboxRepo
+	cfg      RelayConfig
+	mtx      *sync.Mutex
+	handlers []*relayHandler
+}
+
+func NewRelay(repo outbox.OutboxRepo, cfg RelayConfig) *Relay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultRelayInterval
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultRelayRetryDelay
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultRelayMaxAttempts
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &Relay{
+		repo: repo,
+		cfg:  cfg,
+		mtx:  new(sync.Mutex),
+	}
+}
+
+// Handle adds a handler, the name tells it apart in logs. Handlers are
+// added before Run.
+func (r *Relay) Handle(name string, h Handler) {
+	r.handlers = append(r.handlers, &relayHandler{name: name, handle: h})
+}
+
+// Run relays the messages until ctx is done. Messages left in the outbox
+// are relayed by the next Run, e.g. after a restart.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := r.flush(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			r.cfg.Logger.Warn("relay domain events", "error", err)
+		}
+	}
+}
+
+// Flush relays the pending messages until every handler took them all or
+// failed, the failed ones included without waiting for their delay. It
+// returns the errors of the handlers.
+func (r *Relay) Flush(ctx context.Context) error {
+	return r.flush(ctx, time.Time{})
+}
+
+// flush relays to the handlers not waiting for a retry at now, to all
+// when now is zero.
+func (r *Relay) flush(ctx context.Context, now time.Time) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var errs []error
+	for _, h := range r.handlers {
+		if !now.IsZero() && now.Before(h.retryAt) {
+			continue
+		}
+
+		if err := r.feed(ctx, h); err != nil {
+			h.retryAt = time.Now().Add(r.cfg.RetryDelay)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(r.handlers) > 0 {
+		through := r.handlers[0].after
+		for _, h := range r.handlers[1:] {
+			through = min(through, h.after)
+		}
+
+		if err := r.repo.DeleteMessages(ctx, through); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// feed hands the messages after the last one the handler took over to
+// it, until none is left or it fails. A message the handler failed on
+// MaxAttempts times is logged and skipped. The caller must hold r.mtx.
+func (r *Relay) feed(ctx context.Context, h *relayHandler) error {
+	for {
+		messages, err := r.repo.PendingMessages(ctx, h.after, relayBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		for _, m := range messages {
+			if err := h.handle(ctx, eventOf(m)); err != nil {
+				h.attempts++
+				if h.attempts < r.cfg.MaxAttempts {
+					return fmt.Errorf("%s: message %d: %w", h.name, m.ID, err)
+				}
+
+				r.cfg.Logger.Error("dead letter: domain event dropped",
+					"handler", h.name,
+					"message_id", m.ID,
+					"type", m.Type,
+					"attempts", h.attempts,
+					"error", err,
+				)
+			}
+			h.after = m.ID
+			h.attempts = 0
+		}
+	}
+}
+
+func eventOf(m outbox.Message) Event {
+	e := Event{
+		Type:      Type(m.Type),
+		Time:      m.Time,
+		UserID:    m.UserID,
+		MessageID: m.ID,
+	}
+
+	switch {
+	case m.Reminder != nil:
+		e.Data = *m.Reminder
+	case m.Entry != nil:
+		e.Data = *m.Entry
+	case m.User != nil:
+		e.Data = *m.User
+	}
+
+	return e
+}
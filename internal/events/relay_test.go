@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/storage/inmem"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayFlush(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	for _, course := range []string{"Go", "Rust", "C"} {
+		_, err := storage.CreateEntry(ctx, course, time.Now(), 1, "card")
+		require.NoError(t, err)
+	}
+
+	var first, second []string
+	failing := true
+	relay := NewRelay(storage, RelayConfig{})
+	relay.Handle("first", func(ctx context.Context, e Event) error {
+		first = append(first, e.Data.(entry.Entry).Course)
+		return nil
+	})
+	relay.Handle("second", func(ctx context.Context, e Event) error {
+		course := e.Data.(entry.Entry).Course
+		if course == "Rust" && failing {
+			return errors.New("unavailable")
+		}
+		second = append(second, course)
+		return nil
+	})
+
+	steps := []struct {
+		title      string
+		failing    bool
+		wantErr    bool
+		wantFirst  []string
+		wantSecond []string
+		wantLeft   int
+	}{
+		{
+			title:      "sad: the failed handler stops, the other goes on",
+			failing:    true,
+			wantErr:    true,
+			wantFirst:  []string{"Go", "Rust", "C"},
+			wantSecond: []string{"Go"},
+			wantLeft:   2,
+		},
+		{
+			title:      "happy: retried for the failed handler only",
+			wantFirst:  []string{"Go", "Rust", "C"},
+			wantSecond: []string{"Go", "Rust", "C"},
+		},
+		{
+			title:      "happy: nothing left",
+			wantFirst:  []string{"Go", "Rust", "C"},
+			wantSecond: []string{"Go", "Rust", "C"},
+		},
+	}
+
+	for _, step := range steps {
+		failing = step.failing
+		err := relay.Flush(ctx)
+		if step.wantErr {
+			assert.Error(t, err, step.title)
+		} else {
+			assert.NoError(t, err, step.title)
+		}
+
+		assert.Equal(t, step.wantFirst, first, step.title)
+		assert.Equal(t, step.wantSecond, second, step.title)
+
+		left, err := storage.PendingMessages(ctx, 0, 10)
+		require.NoError(t, err, step.title)
+		assert.Len(t, left, step.wantLeft, step.title)
+	}
+}
+
+func TestRelayDeadLetter(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	for _, course := range []string{"Go", "Rust", "C"} {
+		_, err := storage.CreateEntry(ctx, course, time.Now(), 1, "card")
+		require.NoError(t, err)
+	}
+
+	var took []string
+	attempts := 0
+	relay := NewRelay(storage, RelayConfig{MaxAttempts: 3})
+	relay.Handle("poisoned", func(ctx context.Context, e Event) error {
+		course := e.Data.(entry.Entry).Course
+		if course == "Rust" {
+			attempts++
+			return errors.New("can never be sent")
+		}
+		took = append(took, course)
+		return nil
+	})
+
+	for range 2 {
+		assert.Error(t, relay.Flush(ctx))
+	}
+	assert.Equal(t, []string{"Go"}, took, "retried while attempts are left")
+
+	assert.NoError(t, relay.Flush(ctx))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []string{"Go", "C"}, took, "the dead letter is skipped")
+
+	left, err := storage.PendingMessages(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, left, "the outbox is trimmed past the dead letter")
+}
+
+func TestRelayRun(t *testing.T) {
+	storage := inmem.NewStorage()
+	bus := NewBus(0)
+	sub, _, _ := bus.Subscribe(0)
+	defer sub.Close()
+
+	relay := NewRelay(storage, RelayConfig{Interval: time.Millisecond})
+	relay.Handle("bus", BusHandler(bus))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Go(func() { assert.NoError(t, relay.Run(ctx)) })
+	defer wg.Wait()
+	defer cancel()
+
+	u, err := storage.CreateUser(ctx, "ivan", "hash", "", "", "", "", "", false)
+	require.NoError(t, err)
+
+	select {
+	case e := <-sub.C():
+		assert.Equal(t, UserRegistered, e.Type)
+		assert.Equal(t, u.ID, e.UserID)
+		assert.Equal(t, 1, e.MessageID)
+		assert.NotZero(t, e.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event was not relayed")
+	}
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusError       = "error"
+	StatusUnavailable = "unavailable"
+)
+
+var (
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// HealthChecker is a dependency the service needs to serve requests.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckerFunc adapts a function to HealthChecker.
+type HealthCheckerFunc func(ctx context.Context) error
+
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type namedChecker struct {
+	name    string
+	checker HealthChecker
+}
+
+// Health runs the registered checks for the readiness probe.
+type Health struct {
+	timeout      time.Duration
+	mtx          *sync.Mutex
+	checkers     []namedChecker
+	shuttingDown atomic.Bool
+}
+
+// New creates Health where every check is limited by timeout.
+func New(timeout time.Duration) *Health {
+	return &Health{
+		timeout: timeout,
+		mtx:     new(sync.Mutex),
+	}
+}
+
+func (h *Health) Register(name string, checker HealthChecker) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.checkers = append(h.checkers, namedChecker{name: name, checker: checker})
+}
+
+// SetShuttingDown makes the service report not ready from now on,
+// so load balancers stop sending new requests.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Check runs all checks concurrently.
+func (h *Health) Check(ctx context.Context) Report {
+	h.mtx.Lock()
+	checkers := append([]namedChecker(nil), h.checkers...)
+	h.mtx.Unlock()
+
+	report := Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentStatus, len(checkers)),
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mtx sync.Mutex
+	)
+	for _, c := range checkers {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			status := ComponentStatus{Status: StatusOK}
+			if err := c.checker.CheckHealth(ctx); err != nil {
+				status = ComponentStatus{Status: StatusError, Error: err.Error()}
+			}
+
+			mtx.Lock()
+			report.Components[c.name] = status
+			mtx.Unlock()
+		})
+	}
+	wg.Wait()
+
+	for _, status := range report.Components {
+		if status.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	if h.shuttingDown.Load() {
+		report.Status = StatusUnavailable
+		report.Components["server"] = ComponentStatus{Status: StatusError, Error: ErrShuttingDown.Error()}
+	}
+
+	return report
+}
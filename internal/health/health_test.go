@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		title        string
+		checkers     map[string]HealthChecker
+		shuttingDown bool
+		wantStatus   string
+		wantErrors   map[string]string
+	}{
+		{
+			title:      "happy: no checkers",
+			wantStatus: StatusOK,
+		},
+		{
+			title: "happy: all checkers pass",
+			checkers: map[string]HealthChecker{
+				"storage": HealthCheckerFunc(func(ctx context.Context) error { return nil }),
+			},
+			wantStatus: StatusOK,
+		},
+		{
+			title: "sad: failing checker",
+			checkers: map[string]HealthChecker{
+				"storage": HealthCheckerFunc(func(ctx context.Context) error { return nil }),
+				"mailer":  HealthCheckerFunc(func(ctx context.Context) error { return errors.New("smtp is down") }),
+			},
+			wantStatus: StatusUnavailable,
+			wantErrors: map[string]string{"mailer": "smtp is down"},
+		},
+		{
+			title: "sad: checker exceeds timeout",
+			checkers: map[string]HealthChecker{
+				"slow": HealthCheckerFunc(func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}),
+			},
+			wantStatus: StatusUnavailable,
+			wantErrors: map[string]string{"slow": "context deadline exceeded"},
+		},
+		{
+			title:        "sad: shutting down",
+			shuttingDown: true,
+			wantStatus:   StatusUnavailable,
+			wantErrors:   map[string]string{"server": "shutting down"},
+		},
+	}
+
+	for _, tc := range testCases {
+		h := New(10 * time.Millisecond)
+		for name, c := range tc.checkers {
+			h.Register(name, c)
+		}
+		if tc.shuttingDown {
+			h.SetShuttingDown()
+		}
+
+		report := h.Check(t.Context())
+		assert.Equal(t, tc.wantStatus, report.Status, tc.title)
+		assert.Equal(t, tc.wantStatus == StatusOK, report.Ready(), tc.title)
+
+		for name, wantErr := range tc.wantErrors {
+			assert.Equal(t, StatusError, report.Components[name].Status, tc.title)
+			assert.Contains(t, report.Components[name].Error, wantErr, tc.title)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"practice-backend/internal/models/user"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+/*
+pattern: /admin/users?q=x&limit=20&offset=0
+method:  GET
+info:    query params, q searches login, name, surname, patronymic, phone and email
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with users page and total count
+failed:
+  - status code: 400, 401, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := paginationFromQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	users, err := h.userRepo.GetUsers(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if q := strings.ToLower(r.URL.Query().Get("q")); q != "" {
+		users = slices.DeleteFunc(users, func(u user.User) bool {
+			return !userMatches(u, q)
+		})
+	}
+
+	resp := UserListDTO{
+		Users:  make([]UserDTO, 0, limit),
+		Total:  len(users),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for i := offset; i < len(users) && i < offset+limit; i++ {
+		resp.Users = append(resp.Users, NewUserDTO(users[i]))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+pattern: /admin/users/{user_id}
+method:  GET
+info:    in pattern
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of user with their entries
+failed:
+  - status code: 400, 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	usr, ok := h.userForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.entryRepo.GetEntries(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := UserWithEntriesDTO{
+		UserDTO: NewUserDTO(usr),
+		Entries: make([]EntryDTO, 0),
+	}
+	for _, e := range entries {
+		if e.UserID == usr.ID {
+			resp.Entries = append(resp.Entries, NewEntryDTO(e))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+pattern: /admin/users/{user_id}/admin
+method:  PUT to grant, DELETE to revoke
+info:    in pattern, not allowed on own account
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of updated user
+failed:
+  - status code: 400, 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) GrantAdminHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, func(ctx context.Context, id int) (user.User, error) {
+		return h.userRepo.SetAdmin(ctx, id, true)
+	})
+}
+
+func (h *HTTPHandlers) RevokeAdminHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, func(ctx context.Context, id int) (user.User, error) {
+		return h.userRepo.SetAdmin(ctx, id, false)
+	})
+}
+
+/*
+pattern: /admin/users/{user_id}/deactivate, /admin/users/{user_id}/reactivate
+method:  POST
+info:    in pattern, not allowed on own account
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of updated user
+failed:
+  - status code: 400, 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, func(ctx context.Context, id int) (user.User, error) {
+		return h.userRepo.SetDeactivated(ctx, id, true)
+	})
+}
+
+func (h *HTTPHandlers) ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, func(ctx context.Context, id int) (user.User, error) {
+		return h.userRepo.SetDeactivated(ctx, id, false)
+	})
+}
+
+/*
+pattern: /admin/users/{user_id}
+method:  DELETE
+info:    in pattern, not allowed on own account, entries of the user are deleted too
+
+succeed:
+  - status code: 204 No Content
+failed:
+  - status code: 400, 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	usr, ok := h.otherUserForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deleteUserEntries(r.Context(), usr.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.userRepo.DeleteUser(r.Context(), usr.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	LoggerFromContext(r.Context()).Info("user deleted by admin", "deleted_user_id", usr.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateUser applies a field-level update to the user of the request.
+func (h *HTTPHandlers) updateUser(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, id int) (user.User, error)) {
+	usr, ok := h.otherUserForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	usr, err := update(r.Context(), usr.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	LoggerFromContext(r.Context()).Info("user updated by admin",
+		"updated_user_id", usr.ID,
+		"is_admin", usr.IsAdmin,
+		"deactivated", usr.Deactivated,
+	)
+
+	writeJSON(w, http.StatusOK, NewUserDTO(usr))
+}
+
+// userForRequest loads the user addressed by the {user_id} path parameter.
+// On failure it writes the error response itself and returns false.
+func (h *HTTPHandlers) userForRequest(w http.ResponseWriter, r *http.Request) (user.User, bool) {
+	r, span := startSpan(r, "http.userForRequest")
+	defer span.End()
+
+	userID, err := idFromURLParam(r, "user_id")
+	if err != nil {
+		writeError(w, r, err)
+		return user.User{}, false
+	}
+
+	usr, err := h.userRepo.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return user.User{}, false
+	}
+
+	return usr, true
+}
+
+// otherUserForRequest is userForRequest that also rejects the caller's
+// own account, so an admin can't lock themselves out.
+func (h *HTTPHandlers) otherUserForRequest(w http.ResponseWriter, r *http.Request) (user.User, bool) {
+	r, span := startSpan(r, "http.otherUserForRequest")
+	defer span.End()
+
+	callerID, ok := currentUserID(w, r)
+	if !ok {
+		return user.User{}, false
+	}
+
+	usr, ok := h.userForRequest(w, r)
+	if !ok {
+		return user.User{}, false
+	}
+
+	if usr.ID == callerID {
+		writeError(w, r, ErrOwnAccount)
+		return user.User{}, false
+	}
+
+	return usr, true
+}
+
+func paginationFromQuery(r *http.Request) (limit int, offset int, err error) {
+	limit = defaultPageLimit
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return 0, 0, ErrInvalidLimit
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return 0, 0, ErrInvalidOffset
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func userMatches(u user.User, q string) bool {
+	for _, field := range []string{u.Login, u.Name, u.Surname, u.Patronymic, u.Phone, u.Email} {
+		if strings.Contains(strings.ToLower(field), q) {
+			return true
+		}
+	}
+	return false
+}
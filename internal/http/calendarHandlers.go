@@ -0,0 +1,287 @@
+package http
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"practice-backend/internal/calendar"
+	"practice-backend/internal/i18n"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/storage/inmem"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	DefaultCourseDuration = 2 * time.Hour
+
+	// calendarRefreshInterval is how often subscribed apps are asked to
+	// fetch the feed again
+	calendarRefreshInterval = time.Hour
+	// calendarUIDDomain makes the event UIDs unique among the calendars of
+	// an app
+	calendarUIDDomain = "practice-backend"
+)
+
+type CalendarConfig struct {
+	// Location and StartTime place the course start: the entry date at
+	// StartTime, the time of day, in Location. UTC midnight by default.
+	Location  *time.Location
+	StartTime time.Duration
+	// Duration of a course, two hours by default
+	Duration time.Duration
+}
+
+// CalendarHandlers serve the entries of a user as iCalendar files: a feed
+// calendar apps subscribe to by a secret URL, and a file of one entry.
+// Entries show up as tentative until they are approved and as cancelled
+// once rejected. Every change of an entry raises the sequence of its
+// event, so the apps take the new version.
+type CalendarHandlers struct {
+	handlers *HTTPHandlers
+	cfg      CalendarConfig
+}
+
+func NewCalendarHandlers(handlers *HTTPHandlers, cfg CalendarConfig) *CalendarHandlers {
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = DefaultCourseDuration
+	}
+
+	return &CalendarHandlers{
+		handlers: handlers,
+		cfg:      cfg,
+	}
+}
+
+/*
+pattern: /user/me/calendar
+method:  GET
+info:    user from token
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with the URL of the calendar feed
+failed:
+  - status code: 401, 404(feed is off), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *CalendarHandlers) GetCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	usr, err := h.handlers.userRepo.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if usr.CalendarToken == "" {
+		writeError(w, r, inmem.ErrCalendarNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CalendarDTO{URL: feedURL(r, usr.CalendarToken)})
+}
+
+/*
+pattern: /user/me/calendar
+method:  POST
+info:    user from token, turns the feed on with a new secret URL, the
+         old URL stops working
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with the URL of the calendar feed
+failed:
+  - status code: 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *CalendarHandlers) CreateCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	usr, err := h.handlers.userRepo.SetCalendarToken(r.Context(), userID, rand.Text())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CalendarDTO{URL: feedURL(r, usr.CalendarToken)})
+}
+
+/*
+pattern: /user/me/calendar
+method:  DELETE
+info:    user from token, turns the feed off
+
+succeed:
+  - status code: 204 No Content
+failed:
+  - status code: 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *CalendarHandlers) DeleteCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.handlers.userRepo.SetCalendarToken(r.Context(), userID, ""); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+pattern: /calendar/{token}.ics
+method:  GET
+info:    secret token of the feed in pattern, no authorization so calendar
+         apps can subscribe, If-None-Match header with the ETag
+
+succeed:
+  - status code: 200 OK
+  - response body: iCalendar with the entries of the user
+  - status code: 304 Not Modified (entries not changed)
+failed:
+  - status code: 404(unknown token or deactivated user), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *CalendarHandlers) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	usr, err := h.handlers.userRepo.GetUserByCalendarToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if usr.Deactivated {
+		writeError(w, r, inmem.ErrCalendarNotFound)
+		return
+	}
+
+	all, err := h.handlers.entryRepo.GetEntries(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var entries []entry.Entry
+	for _, e := range all {
+		if e.UserID == usr.ID {
+			entries = append(entries, e)
+		}
+	}
+
+	if writeNotModified(w, r, entriesETag(entries)) {
+		return
+	}
+
+	lang := LanguageFromContext(r.Context())
+	// the feed has no token to pick the language of the profile by
+	if preferred, err := i18n.Parse(usr.Language); err == nil && usr.Language != "" {
+		lang = preferred
+		w.Header().Set("Content-Language", string(lang))
+	}
+
+	name, _ := i18n.Message(lang, "calendar.name")
+	cal := calendar.Calendar{
+		Name:            name,
+		Timezone:        h.cfg.Location.String(),
+		RefreshInterval: calendarRefreshInterval,
+	}
+	for _, e := range entries {
+		cal.Events = append(cal.Events, h.event(e, lang))
+	}
+
+	writeCalendar(w, cal, "")
+}
+
+/*
+pattern: /entry/{entry_id}/calendar.ics
+method:  GET
+info:    in pattern, owner of the entry or admin
+
+succeed:
+  - status code: 200 OK
+  - response body: iCalendar with the entry, as a download
+failed:
+  - status code: 400, 401, 403, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *CalendarHandlers) EntryCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.handlers.entryForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cal := calendar.Calendar{
+		Timezone: h.cfg.Location.String(),
+		Events:   []calendar.Event{h.event(e, LanguageFromContext(r.Context()))},
+	}
+
+	writeCalendar(w, cal, fmt.Sprintf("entry-%d.ics", e.ID))
+}
+
+// event is the calendar event of the entry. Its sequence is the entry
+// version, which grows with every change.
+func (h *CalendarHandlers) event(e entry.Entry, lang i18n.Language) calendar.Event {
+	start := e.StartsAt(h.cfg.Location, h.cfg.StartTime)
+	description, _ := i18n.Message(lang, "calendar.status."+e.Status)
+
+	status := calendar.StatusTentative
+	switch e.Status {
+	case "processed":
+		status = calendar.StatusConfirmed
+	case "rejected":
+		status = calendar.StatusCancelled
+	}
+
+	return calendar.Event{
+		UID:         fmt.Sprintf("entry-%d@%s", e.ID, calendarUIDDomain),
+		Sequence:    e.Version - 1,
+		Stamp:       time.Now(),
+		Start:       start,
+		End:         start.Add(h.cfg.Duration),
+		Summary:     e.Course,
+		Description: description,
+		Status:      status,
+	}
+}
+
+// writeCalendar answers with the calendar, as a download named filename
+// when it is set.
+func writeCalendar(w http.ResponseWriter, cal calendar.Calendar, filename string) {
+	w.Header().Set("Content-Type", calendar.ContentType)
+	if filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(cal.Encode())
+}
+
+// feedURL is the absolute URL of the feed with the token, on the host
+// the request came to.
+func feedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+
+	return fmt.Sprintf("%s://%s/api/calendar/%s.ics", scheme, host, token)
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWildcardWithCredentials = errors.New("cors: origin * can't be used with credentials")
+	ErrInvalidOriginPattern    = errors.New("cors: origin pattern may have one * in front of the host")
+
+	ErrCORSRejected = errors.New("cross-origin request is not allowed")
+)
+
+var (
+	corsAllowedMethods = []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	}
+	corsAllowedHeaders = []string{
+		"Accept",
+		"Accept-Language",
+		"Authorization",
+		"Content-Type",
+		"If-Match",
+		"If-None-Match",
+		IdempotencyKeyHeader,
+		"Last-Event-ID",
+		RequestIDHeader,
+	}
+)
+
+// DefaultCORSExposedHeaders are the response headers scripts can read.
+var DefaultCORSExposedHeaders = []string{
+	RequestIDHeader,
+	"Content-Language",
+	"ETag",
+	"Retry-After",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"RateLimit-Policy",
+	IdempotentReplayedHeader,
+}
+
+type CORSConfig struct {
+	// AllowedOrigins are origins like "https://app.example.com", a pattern
+	// like "https://*.example.com" for any subdomain, or "*" for any origin.
+	// Without origins cross-origin requests are not allowed.
+	AllowedOrigins []string
+	// AllowCredentials lets browsers send cookies and the Authorization
+	// header cross-origin.
+	AllowCredentials bool
+	// ExposedHeaders are the response headers scripts can read.
+	ExposedHeaders []string
+	// MaxAge is how long browsers may cache a preflight answer.
+	MaxAge time.Duration
+}
+
+func (c CORSConfig) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return ErrWildcardWithCredentials
+			}
+			continue
+		}
+
+		if strings.Count(origin, "*") > 1 || strings.Contains(origin, "*") && !strings.Contains(origin, "://*.") {
+			return ErrInvalidOriginPattern
+		}
+	}
+
+	return nil
+}
+
+func (c CORSConfig) allowsAnyOrigin() bool {
+	return slices.Contains(c.AllowedOrigins, "*")
+}
+
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		prefix, suffix, ok := strings.Cut(allowed, "*")
+		if !ok || len(origin) <= len(prefix)+len(suffix) {
+			continue
+		}
+		if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+
+		// the subdomain part must be a host name, not a path or a port
+		subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(subdomain, "/:@?#") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CorsMiddleware answers preflight requests and adds CORS headers to
+// requests from allowed origins. Preflights from other origins, or for
+// methods and headers the API doesn't accept, are rejected with 403.
+func CorsMiddleware(cfg CORSConfig) func(next http.Handler) http.Handler {
+	allowedMethods := strings.Join(corsAllowedMethods, ", ")
+	allowedHeaders := strings.Join(corsAllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// the answer depends on Origin even when it is not allowed,
+			// caches must not reuse it for other origins
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !cfg.allowsOrigin(origin) {
+				if preflight {
+					writeError(w, r, ErrCORSRejected)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cfg.allowsAnyOrigin() && !cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposedHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !preflightAllowed(r) {
+				w.Header().Del("Access-Control-Allow-Origin")
+				w.Header().Del("Access-Control-Allow-Credentials")
+				writeError(w, r, ErrCORSRejected)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// preflightAllowed checks the method and headers the browser asks for.
+func preflightAllowed(r *http.Request) bool {
+	if !slices.Contains(corsAllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+		return false
+	}
+
+	for header := range strings.SplitSeq(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+
+		allowed := slices.ContainsFunc(corsAllowedHeaders, func(h string) bool {
+			return strings.EqualFold(h, header)
+		})
+		if !allowed {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorsMiddleware(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		ExposedHeaders:   DefaultCORSExposedHeaders,
+		MaxAge:           10 * time.Minute,
+	}
+
+	testCases := []struct {
+		title          string
+		cfg            CORSConfig
+		method         string
+		headers        map[string]string
+		wantStatus     int
+		wantAllow      string
+		wantMaxAge     string
+		wantExposed    string
+		wantNextCalled bool
+	}{
+		{
+			title:          "happy: same-origin request",
+			cfg:            cfg,
+			method:         http.MethodGet,
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			title:          "happy: allowed origin",
+			cfg:            cfg,
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			wantStatus:     http.StatusOK,
+			wantAllow:      "https://app.example.com",
+			wantExposed:    "X-Request-ID, Content-Language, ETag, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Idempotent-Replayed",
+			wantNextCalled: true,
+		},
+		{
+			title:          "happy: wildcard subdomain",
+			cfg:            cfg,
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://admin.eu.example.org"},
+			wantStatus:     http.StatusOK,
+			wantAllow:      "https://admin.eu.example.org",
+			wantExposed:    "X-Request-ID, Content-Language, ETag, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Idempotent-Replayed",
+			wantNextCalled: true,
+		},
+		{
+			title:          "happy: any origin without credentials",
+			cfg:            CORSConfig{AllowedOrigins: []string{"*"}},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://evil.test"},
+			wantStatus:     http.StatusOK,
+			wantAllow:      "*",
+			wantNextCalled: true,
+		},
+		{
+			title: "happy: preflight",
+			cfg:   cfg,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPatch,
+				"Access-Control-Request-Headers": "authorization, content-type",
+			},
+			method:     http.MethodOptions,
+			wantStatus: http.StatusNoContent,
+			wantAllow:  "https://app.example.com",
+			wantMaxAge: "600",
+		},
+		{
+			title:          "sad: other origin gets no CORS headers",
+			cfg:            cfg,
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://example.org.evil.test"},
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			title:          "sad: wildcard doesn't match the bare domain",
+			cfg:            cfg,
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://example.org"},
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			title: "sad: preflight from other origin",
+			cfg:   cfg,
+			headers: map[string]string{
+				"Origin":                        "https://evil.test",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			method:     http.MethodOptions,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			title: "sad: preflight for unknown header",
+			cfg:   cfg,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "X-Secret",
+			},
+			method:     http.MethodOptions,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			title: "sad: preflight for unknown method",
+			cfg:   cfg,
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "TRACE",
+			},
+			method:     http.MethodOptions,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		var nextCalled bool
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nextCalled = true
+		})
+
+		r := httptest.NewRequest(tc.method, "/api/entry", nil)
+		for k, v := range tc.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+
+		CorsMiddleware(tc.cfg)(next).ServeHTTP(w, r)
+
+		assert.Equal(t, tc.wantStatus, w.Code, tc.title)
+		assert.Equal(t, tc.wantAllow, w.Header().Get("Access-Control-Allow-Origin"), tc.title)
+		assert.Equal(t, tc.wantMaxAge, w.Header().Get("Access-Control-Max-Age"), tc.title)
+		assert.Equal(t, tc.wantExposed, w.Header().Get("Access-Control-Expose-Headers"), tc.title)
+		assert.Equal(t, tc.wantNextCalled, nextCalled, tc.title)
+		assert.Contains(t, w.Header().Values("Vary"), "Origin", tc.title)
+	}
+}
+
+func TestCORSConfigValidate(t *testing.T) {
+	testCases := []struct {
+		title   string
+		cfg     CORSConfig
+		wantErr error
+	}{
+		{
+			title: "happy: origins and pattern",
+			cfg:   CORSConfig{AllowedOrigins: []string{"http://localhost:5173", "https://*.example.com"}},
+		},
+		{
+			title:   "sad: any origin with credentials",
+			cfg:     CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			wantErr: ErrWildcardWithCredentials,
+		},
+		{
+			title:   "sad: wildcard in the middle",
+			cfg:     CORSConfig{AllowedOrigins: []string{"https://app.*.com"}},
+			wantErr: ErrInvalidOriginPattern,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.ErrorIs(t, tc.cfg.Validate(), tc.wantErr, tc.title)
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes every route of configureRouter, TestOpenAPICoversRoutes
+// fails when they drift apart.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>practice-backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+/*
+pattern: /openapi.json
+method:  GET
+info:    OpenAPI 3 document of the API
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of the OpenAPI document
+*/
+
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(openAPISpec)
+}
+
+/*
+pattern: /docs
+method:  GET
+info:    Swagger UI for the OpenAPI document
+
+succeed:
+  - status code: 200 OK
+  - response body: HTML page
+*/
+
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
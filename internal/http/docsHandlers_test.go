@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"practice-backend/internal/health"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/storage/inmem"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas   map[string]json.RawMessage `json:"schemas"`
+		Responses map[string]json.RawMessage `json:"responses"`
+	} `json:"components"`
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	server := NewHTTPServer(*NewHTTPHandlers(nil, nil, nil), ServerConfig{
+		Metrics:  metrics.New(),
+		Health:   health.New(time.Second),
+		Webhooks: inmem.NewStorage(),
+	})
+
+	router, ok := server.configureRouter().(chi.Routes)
+	require.True(t, ok)
+
+	var routes []string
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(openAPISpec, &doc))
+
+	var documented []string
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	assert.ElementsMatch(t, routes, documented, "routes of configureRouter and openapi.json differ")
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(openAPISpec, &doc))
+
+	refs := regexp.MustCompile(`"\$ref": "#/components/(schemas|responses)/([^"]+)"`).FindAllStringSubmatch(string(openAPISpec), -1)
+	require.NotEmpty(t, refs)
+
+	for _, ref := range refs {
+		var ok bool
+		switch ref[1] {
+		case "schemas":
+			_, ok = doc.Components.Schemas[ref[2]]
+		case "responses":
+			_, ok = doc.Components.Responses[ref[2]]
+		}
+		assert.True(t, ok, "unresolved %s", ref[0])
+	}
+}
@@ -3,26 +3,49 @@ package http
 import (
 	"encoding/json"
 	"errors"
-	"net/mail"
+	"net/url"
+	"practice-backend/internal/events"
+	"practice-backend/internal/i18n"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/user"
+	"practice-backend/internal/models/webhook"
 	"practice-backend/internal/validation"
+	"slices"
 	"time"
 )
 
 var (
 	ErrInvalidOrEmptyID = errors.New("invalid or empty id")
 	ErrInvalidStatus    = errors.New("invalid status")
+	ErrAccessDenied     = errors.New("access denied")
+	ErrNotAdmin         = errors.New("user is not admin")
+	ErrOwnAccount       = errors.New("action is not allowed on own account")
+	ErrInvalidLimit     = errors.New("limit is invalid")
+	ErrInvalidOffset    = errors.New("offset is invalid")
+	ErrInvalidEventType = errors.New("event type is unknown")
+	ErrInvalidMessage   = errors.New(`message type must be "subscribe"`)
 
 	ErrLoginIsEmpty      = errors.New("login is empty")
 	ErrPasswordIsEmpty   = errors.New("password is empty")
 	ErrNameIsEmpty       = errors.New("name is empty")
 	ErrSurnameIsEmpty    = errors.New("surname is empty")
 	ErrPatronymicIsEmpty = errors.New("patronymic is empty")
+	ErrEmailIsEmpty      = errors.New("email is empty")
+	ErrInvalidEmail      = errors.New("email is invalid")
+	ErrInvalidLanguage   = errors.New("language is not supported")
+	ErrInvalidEmailKind  = errors.New("email kind is unknown")
 
 	ErrCourseIsEmpty        = errors.New("course is empty")
 	ErrDateIsEmpty          = errors.New("date is empty")
 	ErrInvalidDate          = errors.New("date is invalid")
 	ErrPaymentMethodIsEmpty = errors.New("payment_method is empty")
 	ErrInvalidOrEmptyUserID = errors.New("user_id invalid or empty")
+
+	ErrWebhookURLIsEmpty     = errors.New("url is empty")
+	ErrInvalidWebhookURL     = errors.New("url must be an absolute http or https URL")
+	ErrWebhookEventsIsEmpty  = errors.New("events is empty")
+	ErrInvalidWebhookSecret  = errors.New("secret must be at least 16 characters")
+	ErrInvalidDeliveryStatus = errors.New("delivery status is invalid")
 )
 
 type RegisterUserDTO struct {
@@ -35,46 +58,176 @@ type RegisterUserDTO struct {
 	Email      string `json:"email"`
 }
 
+// Validate also normalizes the phone number.
 func (r *RegisterUserDTO) Validate() error {
-	if _, err := mail.ParseAddress(r.Email); err != nil {
-		return err
+	var v validation.Validator
+
+	v.Required("login", r.Login, ErrLoginIsEmpty)
+	v.Required("password", r.Password, ErrPasswordIsEmpty)
+	v.Required("name", r.Name, ErrNameIsEmpty)
+	v.Required("surname", r.Surname, ErrSurnameIsEmpty)
+	v.Required("patronymic", r.Patronymic, ErrPatronymicIsEmpty)
+	v.Phone("phone", &r.Phone)
+	v.Email("email", r.Email, ErrEmailIsEmpty, ErrInvalidEmail)
+
+	return v.Err()
+}
+
+type LoginUserDTO struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+func (r *LoginUserDTO) Validate() error {
+	var v validation.Validator
+
+	v.Required("login", r.Login, ErrLoginIsEmpty)
+	v.Required("password", r.Password, ErrPasswordIsEmpty)
+
+	return v.Err()
+}
+
+type UserDTO struct {
+	ID          int    `json:"id"`
+	Login       string `json:"login"`
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	IsAdmin     bool   `json:"is_admin"`
+	Deactivated bool   `json:"deactivated"`
+	Language    string `json:"language"`
+	// EmailOptOuts are the kinds of emails the user doesn't get
+	EmailOptOuts []string `json:"email_opt_outs"`
+}
+
+func NewUserDTO(u user.User) UserDTO {
+	return UserDTO{
+		ID:          u.ID,
+		Login:       u.Login,
+		Name:        u.Name,
+		Surname:     u.Surname,
+		Patronymic:  u.Patronymic,
+		Phone:       u.Phone,
+		Email:       u.Email,
+		IsAdmin:     u.IsAdmin,
+		Deactivated: u.Deactivated,
+		Language:    u.Language,
+
+		EmailOptOuts: append([]string{}, u.EmailOptOuts...),
+	}
+}
+
+type UserWithEntriesDTO struct {
+	UserDTO
+	Entries []EntryDTO `json:"entries"`
+}
+
+type UserListDTO struct {
+	Users  []UserDTO `json:"users"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+// UpdateProfileDTO holds a partial profile update, nil fields are left as is.
+type UpdateProfileDTO struct {
+	Name       *string `json:"name"`
+	Surname    *string `json:"surname"`
+	Patronymic *string `json:"patronymic"`
+	Phone      *string `json:"phone"`
+	Email      *string `json:"email"`
+	// Language is "en", "ru" or "" to follow Accept-Language
+	Language *string `json:"language"`
+	// EmailOptOuts replace the kinds of emails the user doesn't get
+	EmailOptOuts *[]string `json:"email_opt_outs"`
+}
+
+// Validate also normalizes the phone number.
+func (u *UpdateProfileDTO) Validate() error {
+	var v validation.Validator
+
+	if u.Name != nil {
+		v.Required("name", *u.Name, ErrNameIsEmpty)
 	}
-	if r.Login == "" {
-		return ErrLoginIsEmpty
+	if u.Surname != nil {
+		v.Required("surname", *u.Surname, ErrSurnameIsEmpty)
 	}
-	if r.Name == "" {
-		return ErrNameIsEmpty
+	if u.Patronymic != nil {
+		v.Required("patronymic", *u.Patronymic, ErrPatronymicIsEmpty)
 	}
-	if r.Password == "" {
-		return ErrPasswordIsEmpty
+	if u.Phone != nil {
+		v.Phone("phone", u.Phone)
 	}
-	if r.Patronymic == "" {
-		return ErrPatronymicIsEmpty
+	if u.Email != nil {
+		v.Email("email", *u.Email, ErrEmailIsEmpty, ErrInvalidEmail)
 	}
-	if err := validation.ValidatePhone(r.Phone); err != nil {
-		return err
+	if u.Language != nil && *u.Language != "" {
+		_, err := i18n.Parse(*u.Language)
+		v.Check(err == nil, "language", ErrInvalidLanguage)
 	}
-	if r.Surname == "" {
-		return ErrSurnameIsEmpty
+	if u.EmailOptOuts != nil {
+		for _, kind := range *u.EmailOptOuts {
+			v.Check(slices.Contains(user.EmailKinds, kind), "email_opt_outs", ErrInvalidEmailKind)
+		}
 	}
 
-	return nil
+	return v.Err()
 }
 
-type LoginUserDTO struct {
-	Login    string `json:"login"`
+// Apply copies the set fields to the user.
+func (u *UpdateProfileDTO) Apply(usr *user.User) {
+	if u.Name != nil {
+		usr.Name = *u.Name
+	}
+	if u.Surname != nil {
+		usr.Surname = *u.Surname
+	}
+	if u.Patronymic != nil {
+		usr.Patronymic = *u.Patronymic
+	}
+	if u.Phone != nil {
+		usr.Phone = *u.Phone
+	}
+	if u.Email != nil {
+		usr.Email = *u.Email
+	}
+	if u.Language != nil {
+		usr.Language = ""
+		if lang, err := i18n.Parse(*u.Language); err == nil {
+			usr.Language = string(lang)
+		}
+	}
+	if u.EmailOptOuts != nil {
+		usr.EmailOptOuts = slices.Compact(slices.Sorted(slices.Values(*u.EmailOptOuts)))
+	}
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (c *ChangePasswordDTO) Validate() error {
+	var v validation.Validator
+
+	v.Required("current_password", c.CurrentPassword, ErrPasswordIsEmpty)
+	v.Required("new_password", c.NewPassword, ErrPasswordIsEmpty)
+
+	return v.Err()
+}
+
+type DeleteAccountDTO struct {
 	Password string `json:"password"`
 }
 
-func (r *LoginUserDTO) Validate() error {
-	if r.Login == "" {
-		return ErrLoginIsEmpty
-	}
-	if r.Password == "" {
-		return ErrPasswordIsEmpty
-	}
+func (d *DeleteAccountDTO) Validate() error {
+	var v validation.Validator
 
-	return nil
+	v.Required("password", d.Password, ErrPasswordIsEmpty)
+
+	return v.Err()
 }
 
 type CreateEntryDTO struct {
@@ -85,44 +238,61 @@ type CreateEntryDTO struct {
 }
 
 func (c *CreateEntryDTO) Validate() error {
-	if c.Course == "" {
-		return ErrCourseIsEmpty
-	}
-
-	if c.Date == "" {
-		return ErrDateIsEmpty
-	}
+	var v validation.Validator
 
-	// "2025-10-05" valid
-	if _, err := time.Parse(time.DateOnly, c.Date); err != nil {
-		return ErrInvalidDate
-	}
+	v.Required("course", c.Course, ErrCourseIsEmpty)
 
-	if c.PaymentMethod == "" {
-		return ErrPaymentMethodIsEmpty
+	if v.Required("date", c.Date, ErrDateIsEmpty) {
+		// "2025-10-05" valid
+		_, err := time.Parse(time.DateOnly, c.Date)
+		v.Check(err == nil, "date", ErrInvalidDate)
 	}
 
-	if c.UserID <= 0 {
-		return ErrInvalidOrEmptyUserID
-	}
+	v.Required("payment_method", c.PaymentMethod, ErrPaymentMethodIsEmpty)
+	v.Check(c.UserID > 0, "user_id", ErrInvalidOrEmptyUserID)
 
-	return nil
+	return v.Err()
 }
 
 type UpdateEntryDTO struct {
-	ID     int    `json:"id"`
 	Status string `json:"status"`
 }
 
 func (u *UpdateEntryDTO) Validate() error {
-	if u.ID < 0 {
-		return ErrInvalidOrEmptyID
-	}
-	if !(u.Status == "not processed" || u.Status == "processed" || u.Status == "rejected") {
-		return ErrInvalidStatus
+	var v validation.Validator
+
+	if v.Required("status", u.Status, ErrInvalidStatus) {
+		v.Check(validEntryStatus(u.Status), "status", ErrInvalidStatus)
 	}
 
-	return nil
+	return v.Err()
+}
+
+func validEntryStatus(status string) bool {
+	return status == "not processed" || status == "processed" || status == "rejected"
+}
+
+type EntryDTO struct {
+	ID            int    `json:"id"`
+	Course        string `json:"course"`
+	Date          string `json:"date"`
+	UserID        int    `json:"user_id"`
+	PaymentMethod string `json:"payment_method"`
+	Status        string `json:"status"`
+	// Version is also sent as the ETag header
+	Version int `json:"version"`
+}
+
+func NewEntryDTO(e entry.Entry) EntryDTO {
+	return EntryDTO{
+		ID:            e.ID,
+		Course:        e.Course,
+		Date:          e.Date.Format(time.DateOnly),
+		UserID:        e.UserID,
+		PaymentMethod: e.PaymentMethod,
+		Status:        e.Status,
+		Version:       e.Version,
+	}
 }
 
 // type GetUserByLoginDTO struct {
@@ -136,20 +306,148 @@ func (u *UpdateEntryDTO) Validate() error {
 // 	return nil
 // }
 
-type ErrorDTO struct {
-	Message string    `json:"message"`
-	Time    time.Time `json:"time"`
+// SubscribeDTO is a message of a WebSocket client that narrows the events
+// it gets. Empty lists mean any; courses and statuses only narrow entry
+// events.
+type SubscribeDTO struct {
+	Type     string   `json:"type"`
+	Types    []string `json:"types"`
+	Courses  []string `json:"courses"`
+	Statuses []string `json:"statuses"`
 }
 
-func NewErrorDTO(err error) ErrorDTO {
-	return ErrorDTO{
-		Message: err.Error(),
-		Time:    time.Now(),
+func (s *SubscribeDTO) Validate() error {
+	var v validation.Validator
+
+	v.Check(s.Type == "subscribe", "type", ErrInvalidMessage)
+
+	for _, t := range s.Types {
+		v.Check(slices.Contains(wsEventTypes, events.Type(t)), "types", ErrInvalidEventType)
 	}
+	for _, status := range s.Statuses {
+		v.Check(validEntryStatus(status), "statuses", ErrInvalidStatus)
+	}
+
+	return v.Err()
 }
 
-func (e ErrorDTO) String() string {
-	b, _ := json.MarshalIndent(e, "", "    ")
+// webhookEventTypes are the events webhooks can subscribe to.
+var webhookEventTypes = []events.Type{
+	events.EntryCreated,
+	events.EntryStatusChanged,
+}
+
+const minWebhookSecretLen = 16
+
+// CreateWebhookDTO subscribes a URL to events. A secret is generated when
+// it is empty.
+type CreateWebhookDTO struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+func (c *CreateWebhookDTO) Validate() error {
+	var v validation.Validator
+
+	if v.Required("url", c.URL, ErrWebhookURLIsEmpty) {
+		u, err := url.Parse(c.URL)
+		v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "url", ErrInvalidWebhookURL)
+	}
+
+	if len(c.Events) == 0 {
+		v.Add("events", validation.CodeRequired, ErrWebhookEventsIsEmpty)
+	}
+	for _, t := range c.Events {
+		v.Check(slices.Contains(webhookEventTypes, events.Type(t)), "events", ErrInvalidEventType)
+	}
+
+	v.Check(c.Secret == "" || len(c.Secret) >= minWebhookSecretLen, "secret", ErrInvalidWebhookSecret)
+
+	return v.Err()
+}
+
+type WebhookDTO struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only shown in the answer to the creation
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhookDTO(w webhook.Webhook) WebhookDTO {
+	return WebhookDTO{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+type WebhookListDTO struct {
+	Webhooks []WebhookDTO `json:"webhooks"`
+}
+
+type DeliveryDTO struct {
+	ID        int             `json:"id"`
+	WebhookID int             `json:"webhook_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// NextAttemptAt is set for pending deliveries only
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func NewDeliveryDTO(d webhook.Delivery) DeliveryDTO {
+	dto := DeliveryDTO{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == webhook.StatusPending {
+		dto.NextAttemptAt = &d.NextAttemptAt
+	}
+	if !d.LastAttemptAt.IsZero() {
+		dto.LastAttemptAt = &d.LastAttemptAt
+	}
+
+	return dto
+}
+
+type DeliveryListDTO struct {
+	Deliveries []DeliveryDTO `json:"deliveries"`
+	Total      int           `json:"total"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+}
+
+type CalendarDTO struct {
+	// URL of the calendar feed, secret: anyone with it sees the entries
+	URL string `json:"url"`
+}
+
+// ErrorDTO is the envelope of every error response.
+type ErrorDTO struct {
+	Code      ErrorCode                `json:"code"`
+	Message   string                   `json:"message"`
+	Fields    map[string]FieldErrorDTO `json:"fields,omitempty"`
+	RequestID string                   `json:"request_id,omitempty"`
+	Time      time.Time                `json:"time"`
+}
 
-	return string(b)
+type FieldErrorDTO struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
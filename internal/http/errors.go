@@ -0,0 +1,262 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"practice-backend/internal/i18n"
+	"practice-backend/internal/idempotency"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/storage/inmem/ilist"
+	"practice-backend/internal/validation"
+	"time"
+)
+
+// ErrorCode is a stable, machine-readable error identifier. Clients should
+// match on it instead of the message.
+type ErrorCode string
+
+const (
+	CodeInvalidJSON           ErrorCode = "INVALID_JSON"
+	CodeValidationFailed      ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidID             ErrorCode = "INVALID_ID"
+	CodeInvalidQuery          ErrorCode = "INVALID_QUERY"
+	CodeInvalidCredentials    ErrorCode = "INVALID_CREDENTIALS"
+	CodeInvalidToken          ErrorCode = "INVALID_TOKEN"
+	CodeUserDeactivated       ErrorCode = "USER_DEACTIVATED"
+	CodeAccessDenied          ErrorCode = "ACCESS_DENIED"
+	CodeNotAdmin              ErrorCode = "NOT_ADMIN"
+	CodeOwnAccount            ErrorCode = "OWN_ACCOUNT"
+	CodeUserExists            ErrorCode = "USER_EXISTS"
+	CodeUserNotFound          ErrorCode = "USER_NOT_FOUND"
+	CodeEntryNotFound         ErrorCode = "ENTRY_NOT_FOUND"
+	CodeWebhookNotFound       ErrorCode = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound      ErrorCode = "DELIVERY_NOT_FOUND"
+	CodeCalendarNotFound      ErrorCode = "CALENDAR_NOT_FOUND"
+	CodePreconditionFailed    ErrorCode = "PRECONDITION_FAILED"
+	CodePreconditionRequired  ErrorCode = "PRECONDITION_REQUIRED"
+	CodeRouteNotFound         ErrorCode = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed      ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeCORSRejected          ErrorCode = "CORS_REJECTED"
+	CodeRateLimited           ErrorCode = "RATE_LIMITED"
+	CodeInvalidIdempotencyKey ErrorCode = "INVALID_IDEMPOTENCY_KEY"
+	CodeIdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	CodeIdempotencyKeyReused  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	CodeInternal              ErrorCode = "INTERNAL"
+)
+
+// APIError is an error with everything needed to answer the client.
+type APIError struct {
+	Code    ErrorCode
+	Status  int
+	Message string
+	// Fields maps a request field to what is wrong with it
+	Fields map[string]FieldErrorDTO
+	Err    error
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// errorMapping describes how a domain error is answered. A non-empty field
+// means the error is about that request field.
+type errorMapping struct {
+	err    error
+	code   ErrorCode
+	status int
+	field  string
+}
+
+// errorMappings is the single place where domain errors get their codes
+// and HTTP statuses. The first match wins.
+var errorMappings = []errorMapping{
+	{err: inmem.ErrUserAlreadyExist, code: CodeUserExists, status: http.StatusConflict},
+	{err: inmem.ErrUserNotFound, code: CodeUserNotFound, status: http.StatusNotFound},
+	{err: inmem.ErrEntryNotFound, code: CodeEntryNotFound, status: http.StatusNotFound},
+	{err: inmem.ErrWebhookNotFound, code: CodeWebhookNotFound, status: http.StatusNotFound},
+	{err: inmem.ErrDeliveryNotFound, code: CodeDeliveryNotFound, status: http.StatusNotFound},
+	{err: inmem.ErrCalendarNotFound, code: CodeCalendarNotFound, status: http.StatusNotFound},
+	{err: ilist.ErrInvalidID, code: CodeInvalidID, status: http.StatusBadRequest},
+
+	{err: auth.ErrInvalidCredentials, code: CodeInvalidCredentials, status: http.StatusBadRequest},
+	{err: auth.ErrInvalidToken, code: CodeInvalidToken, status: http.StatusUnauthorized},
+	{err: auth.ErrUserDeactivated, code: CodeUserDeactivated, status: http.StatusForbidden},
+
+	{err: ErrAccessDenied, code: CodeAccessDenied, status: http.StatusForbidden},
+	{err: ErrNotAdmin, code: CodeNotAdmin, status: http.StatusForbidden},
+	{err: ErrOwnAccount, code: CodeOwnAccount, status: http.StatusBadRequest},
+	{err: ErrInvalidOrEmptyID, code: CodeInvalidID, status: http.StatusBadRequest},
+	{err: ErrCORSRejected, code: CodeCORSRejected, status: http.StatusForbidden},
+	{err: inmem.ErrEntryVersionMismatch, code: CodePreconditionFailed, status: http.StatusPreconditionFailed},
+	{err: ErrIfMatchRequired, code: CodePreconditionRequired, status: http.StatusPreconditionRequired},
+	{err: ErrRateLimited, code: CodeRateLimited, status: http.StatusTooManyRequests},
+	{err: ErrInvalidIdempotencyKey, code: CodeInvalidIdempotencyKey, status: http.StatusBadRequest},
+	{err: idempotency.ErrInProgress, code: CodeIdempotencyInProgress, status: http.StatusConflict},
+	{err: idempotency.ErrKeyReused, code: CodeIdempotencyKeyReused, status: http.StatusUnprocessableEntity},
+	{err: ErrInvalidLimit, code: CodeInvalidQuery, status: http.StatusBadRequest, field: "limit"},
+	{err: ErrInvalidOffset, code: CodeInvalidQuery, status: http.StatusBadRequest, field: "offset"},
+	{err: ErrInvalidDeliveryStatus, code: CodeInvalidQuery, status: http.StatusBadRequest, field: "status"},
+}
+
+// toAPIError maps any error to an APIError. Unknown errors become
+// an internal error, their text is not shown to the client.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		return validationFailed(validationErrs)
+	}
+
+	for _, m := range errorMappings {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+
+		apiErr = &APIError{
+			Code:    m.code,
+			Status:  m.status,
+			Message: m.err.Error(),
+			Err:     err,
+		}
+		if m.field != "" {
+			apiErr.Fields = map[string]FieldErrorDTO{
+				m.field: {Code: validation.CodeInvalid, Message: m.err.Error()},
+			}
+		}
+		return apiErr
+	}
+
+	return &APIError{
+		Code:    CodeInternal,
+		Status:  http.StatusInternalServerError,
+		Message: "internal error",
+		Err:     err,
+	}
+}
+
+// validationFailed answers with every invalid field of the request.
+func validationFailed(errs validation.Errors) *APIError {
+	fields := make(map[string]FieldErrorDTO, len(errs))
+	for _, fe := range errs {
+		fields[fe.Field] = FieldErrorDTO{Code: fe.Code, Message: fe.Err.Error()}
+	}
+
+	return &APIError{
+		Code:    CodeValidationFailed,
+		Status:  http.StatusUnprocessableEntity,
+		Message: "request is invalid",
+		Fields:  fields,
+		Err:     errs,
+	}
+}
+
+// invalidJSON wraps a request body decoding error.
+func invalidJSON(err error) *APIError {
+	return &APIError{
+		Code:    CodeInvalidJSON,
+		Status:  http.StatusBadRequest,
+		Message: "request body is not valid JSON: " + err.Error(),
+		Err:     err,
+	}
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	_, span := startSpan(r, "http.decodeJSON")
+	defer span.End()
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return invalidJSON(err)
+	}
+	return nil
+}
+
+// validate validates the decoded body in a span of its own.
+func validate(r *http.Request, v interface{ Validate() error }) error {
+	_, span := startSpan(r, "http.validate")
+	defer span.End()
+
+	return v.Validate()
+}
+
+// writeError answers with the error envelope in the request language.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, errDTO := newErrorDTO(r, err)
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	writeJSON(w, status, errDTO)
+}
+
+// newErrorDTO builds the error envelope of err and its status code.
+// Internal errors are logged with the request logger.
+func newErrorDTO(r *http.Request, err error) (int, ErrorDTO) {
+	apiErr := toAPIError(err)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		LoggerFromContext(r.Context()).Error("request failed", "error", err)
+	}
+
+	lang := LanguageFromContext(r.Context())
+
+	var fields map[string]FieldErrorDTO
+	if len(apiErr.Fields) > 0 {
+		fields = make(map[string]FieldErrorDTO, len(apiErr.Fields))
+		for field, fe := range apiErr.Fields {
+			fe.Message = localize(lang, fe.Message, field+"."+fe.Code, fe.Code)
+			fields[field] = fe
+		}
+	}
+
+	errDTO := ErrorDTO{
+		Code:      apiErr.Code,
+		Message:   localize(lang, apiErr.Message, string(apiErr.Code)),
+		Fields:    fields,
+		RequestID: RequestIDFromContext(r.Context()),
+		Time:      time.Now(),
+	}
+
+	return apiErr.Status, errDTO
+}
+
+// localize returns the message of the first key found in the catalog,
+// or fallback when there is none.
+func localize(lang i18n.Language, fallback string, keys ...string) string {
+	for _, key := range keys {
+		if msg, ok := i18n.Message(lang, key); ok {
+			return msg
+		}
+	}
+
+	return fallback
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &APIError{
+		Code:    CodeRouteNotFound,
+		Status:  http.StatusNotFound,
+		Message: "route not found",
+	})
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &APIError{
+		Code:    CodeMethodNotAllowed,
+		Status:  http.StatusMethodNotAllowed,
+		Message: "method not allowed",
+	})
+}
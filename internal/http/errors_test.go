@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/validation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError(t *testing.T) {
+	testCases := []struct {
+		title      string
+		err        error
+		wantStatus int
+		wantCode   ErrorCode
+		wantFields map[string]FieldErrorDTO
+	}{
+		{
+			title:      "user already exists",
+			err:        inmem.ErrUserAlreadyExist,
+			wantStatus: http.StatusConflict,
+			wantCode:   CodeUserExists,
+		},
+		{
+			title:      "wrapped not found",
+			err:        fmt.Errorf("get entry: %w", inmem.ErrEntryNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeEntryNotFound,
+		},
+		{
+			title:      "invalid token",
+			err:        auth.ErrInvalidToken,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeInvalidToken,
+		},
+		{
+			title:      "validation errors of all fields",
+			err:        (&LoginUserDTO{}).Validate(),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   CodeValidationFailed,
+			wantFields: map[string]FieldErrorDTO{
+				"login":    {Code: validation.CodeRequired, Message: ErrLoginIsEmpty.Error()},
+				"password": {Code: validation.CodeRequired, Message: ErrPasswordIsEmpty.Error()},
+			},
+		},
+		{
+			title:      "query error has field",
+			err:        ErrInvalidLimit,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidQuery,
+			wantFields: map[string]FieldErrorDTO{
+				"limit": {Code: validation.CodeInvalid, Message: ErrInvalidLimit.Error()},
+			},
+		},
+		{
+			title:      "invalid json",
+			err:        invalidJSON(errors.New("unexpected EOF")),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidJSON,
+		},
+		{
+			title:      "unknown error is internal",
+			err:        errors.New("disk is on fire"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   CodeInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			w := httptest.NewRecorder()
+
+			writeError(w, r, tc.err)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+			var errDTO ErrorDTO
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&errDTO))
+			assert.Equal(t, tc.wantCode, errDTO.Code)
+			assert.Equal(t, tc.wantFields, errDTO.Fields)
+			assert.NotContains(t, errDTO.Message, "disk is on fire")
+		})
+	}
+}
+
+func TestWriteErrorLocalized(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
+	w := httptest.NewRecorder()
+
+	handler := LanguageMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, (&LoginUserDTO{Login: "ivan"}).Validate())
+	}))
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, "ru", w.Header().Get("Content-Language"))
+
+	var errDTO ErrorDTO
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&errDTO))
+	assert.Equal(t, "запрос содержит ошибки", errDTO.Message)
+	assert.Equal(t, map[string]FieldErrorDTO{
+		"password": {Code: validation.CodeRequired, Message: "не указан пароль"},
+	}, errDTO.Fields)
+}
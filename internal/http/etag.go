@@ -0,0 +1,67 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/storage/inmem"
+	"strconv"
+	"strings"
+)
+
+var ErrIfMatchRequired = errors.New("If-Match header with the entry ETag is required")
+
+// entryETag is the version of the entry, it changes with every update.
+func entryETag(e entry.Entry) string {
+	return `"` + strconv.Itoa(e.Version) + `"`
+}
+
+// entriesETag changes when an entry of the list is added, changed or removed.
+// IDs are never reused, so a list can't get back an old ETag.
+func entriesETag(entries []entry.Entry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(strconv.Itoa(e.ID) + ":" + strconv.Itoa(e.Version) + ";"))
+	}
+
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// ifMatchVersion returns the entry version the request is conditional on,
+// entry.AnyVersion for "If-Match: *". The header is required so that
+// changes are not made to an entry the client has not seen.
+func ifMatchVersion(r *http.Request) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return 0, ErrIfMatchRequired
+	}
+	if ifMatch == "*" {
+		return entry.AnyVersion, nil
+	}
+
+	// weak and listed ETags can't be checked atomically, they never match
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(ifMatch, `"`), `"`))
+	if err != nil || version <= 0 || !strings.HasPrefix(ifMatch, `"`) || !strings.HasSuffix(ifMatch, `"`) {
+		return 0, inmem.ErrEntryVersionMismatch
+	}
+
+	return version, nil
+}
+
+// writeNotModified answers 304 when If-None-Match has etag. Otherwise it
+// only sets the ETag header and returns false.
+func writeNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	for tag := range strings.SplitSeq(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/storage/inmem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	testCases := []struct {
+		title       string
+		ifMatch     string
+		wantVersion int
+		wantErr     error
+	}{
+		{title: "happy: version", ifMatch: `"3"`, wantVersion: 3},
+		{title: "happy: any version", ifMatch: "*", wantVersion: entry.AnyVersion},
+		{title: "sad: no header", wantErr: ErrIfMatchRequired},
+		{title: "sad: weak etag", ifMatch: `W/"3"`, wantErr: inmem.ErrEntryVersionMismatch},
+		{title: "sad: unquoted", ifMatch: "3", wantErr: inmem.ErrEntryVersionMismatch},
+		{title: "sad: list of etags", ifMatch: `"3", "4"`, wantErr: inmem.ErrEntryVersionMismatch},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodPatch, "/api/entry/1", nil)
+		if tc.ifMatch != "" {
+			r.Header.Set("If-Match", tc.ifMatch)
+		}
+
+		version, err := ifMatchVersion(r)
+
+		assert.ErrorIs(t, err, tc.wantErr, tc.title)
+		assert.Equal(t, tc.wantVersion, version, tc.title)
+	}
+}
+
+func TestWriteNotModified(t *testing.T) {
+	testCases := []struct {
+		title       string
+		ifNoneMatch string
+		want        bool
+	}{
+		{title: "happy: same etag", ifNoneMatch: `"2"`, want: true},
+		{title: "happy: weak etag in a list", ifNoneMatch: `"1", W/"2"`, want: true},
+		{title: "happy: any", ifNoneMatch: "*", want: true},
+		{title: "sad: other etag", ifNoneMatch: `"1"`},
+		{title: "sad: no header"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/api/entry/1", nil)
+		if tc.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", tc.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+
+		got := writeNotModified(w, r, entryETag(entry.Entry{Version: 2}))
+
+		assert.Equal(t, tc.want, got, tc.title)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"), tc.title)
+		if tc.want {
+			assert.Equal(t, http.StatusNotModified, w.Code, tc.title)
+		}
+	}
+}
+
+func TestEntriesETag(t *testing.T) {
+	entries := []entry.Entry{{ID: 1, Version: 1}, {ID: 2, Version: 1}}
+	etag := entriesETag(entries)
+
+	assert.Equal(t, etag, entriesETag([]entry.Entry{{ID: 1, Version: 1}, {ID: 2, Version: 1}}))
+	assert.NotEqual(t, etag, entriesETag([]entry.Entry{{ID: 1, Version: 2}, {ID: 2, Version: 1}}), "changed entry")
+	assert.NotEqual(t, etag, entriesETag(entries[:1]), "removed entry")
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"practice-backend/internal/events"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/user"
+	"practice-backend/internal/services/auth"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultSSEHeartbeat = 15 * time.Second
+	// sseRetry is how long browsers wait before they reconnect
+	sseRetry = 3 * time.Second
+	// sseReset tells the client that events were missed and it has to
+	// reload the entries
+	sseReset = "reset"
+)
+
+/*
+pattern: /entry/events
+method:  GET
+info:    Server-Sent Events of entry status changes, own entries or all for
+         admins; Last-Event-ID header or last_event_id query to resume
+
+succeed:
+  - status code: 200 OK
+  - response body: text/event-stream, events with id, event type and JSON
+    data of the entry; "reset" when missed events are no longer buffered;
+    comment lines as heartbeat
+failed:
+  - status code: 401, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+// EntryEventsHandler streams until the client disconnects, the
+// subscription is dropped for not keeping up, the user is deactivated or
+// deleted, or done is closed. A role change applies to the next events.
+func EntryEventsHandler(bus *events.Bus, authService Auth, heartbeat time.Duration, done <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUserID(w, r)
+		if !ok {
+			return
+		}
+
+		isAdmin, err := authService.IsAdmin(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, auth.ErrInvalidToken)
+			return
+		}
+
+		visible := func(e events.Event) bool {
+			return e.Type == events.EntryStatusChanged && eventVisible(e, userID, isAdmin)
+		}
+
+		sub, missed, complete := bus.Subscribe(lastEventID(r))
+		defer sub.Close()
+
+		rc := http.NewResponseController(w)
+		// the stream outlives any write timeout of the server
+		rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+		if !complete {
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", sseReset)
+		}
+		for _, e := range missed {
+			if visible(e) {
+				writeSSE(w, e)
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-done:
+				return
+			case e, ok := <-sub.C():
+				if !ok {
+					// the client reconnects and resumes after the last ID
+					return
+				}
+				if userChanged(e, userID) {
+					if isAdmin, err = streamAccess(r.Context(), authService, userID); err != nil {
+						return
+					}
+				}
+				if !visible(e) {
+					continue
+				}
+				writeSSE(w, e)
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID is where the client resumes. Browsers send the header on
+// reconnect, the query parameter is for the first connection.
+func lastEventID(r *http.Request) uint64 {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("last_event_id")
+	}
+
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+// userChanged tells whether the event changed the user with userID, whose
+// streams then look up the role and the access of the user again.
+func userChanged(e events.Event, userID int) bool {
+	return (e.Type == events.UserUpdated || e.Type == events.UserDeleted) && e.UserID == userID
+}
+
+// streamAccess tells whether the user may still stream and is an admin.
+// It fails when the user was deactivated or deleted.
+func streamAccess(ctx context.Context, authService Auth, userID int) (isAdmin bool, err error) {
+	if err := authService.Authenticate(ctx, userID); err != nil {
+		return false, err
+	}
+
+	return authService.IsAdmin(ctx, userID)
+}
+
+// eventVisible tells whether the user may see the event: admins see all,
+// users the events of their own entries.
+func eventVisible(e events.Event, userID int, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+
+	switch e.Type {
+	case events.EntryCreated, events.EntryStatusChanged:
+		return e.UserID == userID
+	}
+
+	return false
+}
+
+// EventData is the event data as the API shows it, in event streams and
+// webhook payloads alike.
+func EventData(e events.Event) any {
+	switch data := e.Data.(type) {
+	case entry.Entry:
+		return NewEntryDTO(data)
+	case user.User:
+		return NewUserDTO(data)
+	}
+
+	return e.Data
+}
+
+func writeSSE(w http.ResponseWriter, e events.Event) {
+	b, err := json.Marshal(EventData(e))
+	if err != nil {
+		b = []byte("{}")
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b)
+}
@@ -2,14 +2,16 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"practice-backend/internal/models/entry"
 	"practice-backend/internal/models/user"
+	"practice-backend/internal/services/auth"
 	"practice-backend/internal/storage/inmem"
 	"strconv"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // todo: implement handler get user by login(pattern: /user/{login})
@@ -25,10 +27,31 @@ type Auth interface {
 		ctx context.Context,
 		user user.User,
 	) (userID int, err error)
+	RefreshToken(
+		ctx context.Context,
+		userID int,
+		authTime time.Time,
+	) (token string, err error)
+	Authenticate(
+		ctx context.Context,
+		userID int,
+	) error
 	IsAdmin(
 		ctx context.Context,
 		userID int,
 	) (bool, error)
+	ChangePassword(
+		ctx context.Context,
+		userID int,
+		currentPassword string,
+		newPassword string,
+	) error
+	DeleteAccount(
+		ctx context.Context,
+		userID int,
+		password string,
+		deleteData func(ctx context.Context) error,
+	) error
 }
 
 type HTTPHandlers struct {
@@ -52,28 +75,26 @@ func NewHTTPHandlers(
 /*
 pattern: /user/register
 method:  POST
-info:    JSON in HTTP request body
+info:    JSON in HTTP request body, optional Idempotency-Key header
 
 succeed:
   - status code: 201 Created
-  - response body: JSON of created created user
+  - response body: JSON with id of the created user
 failed:
-  - status code: 400, 409(Conflict), 500
-  - response body: JSON with error + time
+  - status code: 400, 422(validation), 409(Conflict), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
 func (h *HTTPHandlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	var registerDTO RegisterUserDTO
 
-	if err := json.NewDecoder(r.Body).Decode(&registerDTO); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := decodeJSON(r, &registerDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := registerDTO.Validate(); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := validate(r, &registerDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -90,13 +111,7 @@ func (h *HTTPHandlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := h.authService.Register(r.Context(), *user)
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		if errors.Is(err, inmem.ErrUserAlreadyExist) {
-			http.Error(w, errDTO.String(), http.StatusConflict)
-			return
-		}
-
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -106,8 +121,7 @@ func (h *HTTPHandlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		ID: userID,
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusCreated, resp)
 }
 
 /*
@@ -119,29 +133,26 @@ succeed:
   - status code: 200 OK
   - response body: JSON with token
 failed:
-  - status code: 400, 500
-  - response body: JSON with error + time
+  - status code: 400, 422(validation), 403(deactivated), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
 func (h *HTTPHandlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var loginDTO LoginUserDTO
 
-	if err := json.NewDecoder(r.Body).Decode(&loginDTO); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := decodeJSON(r, &loginDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := loginDTO.Validate(); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := validate(r, &loginDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	token, err := h.authService.Login(r.Context(), loginDTO.Login, loginDTO.Password)
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -151,35 +162,79 @@ func (h *HTTPHandlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		Token: token,
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+pattern: /user/token/refresh
+method:  POST
+info:    user from token, the token must still be valid and from a login
+         no longer than 7 days ago
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with a new token
+failed:
+  - status code: 401, 403(deactivated), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := validateToken(r)
+	if err != nil {
+		writeError(w, r, auth.ErrInvalidToken)
+		return
+	}
+	authTime, err := authTimeFromToken(token)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	refreshed, err := h.authService.RefreshToken(r.Context(), userID, authTime)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := struct {
+		Token string `json:"token"`
+	}{
+		Token: refreshed,
+	}
+
+	writeJSON(w, http.StatusOK, resp)
 }
 
 /*
 pattern: /entry
 method:  POST
-info:    JSON of created entry
+info:    JSON with course, date, user_id and payment_method,
+         optional Idempotency-Key header
 
 succeed:
   - status code: 201 Created
-  - response body: JSON with token
+  - response body: JSON of created entry
 failed:
-  - status code: 400, 500
-  - response body: JSON with error + time
+  - status code: 400, 409(idempotency key in progress), 422(validation), 429, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
 func (h *HTTPHandlers) CreateEntryHandler(w http.ResponseWriter, r *http.Request) {
 	var createEntryDTO CreateEntryDTO
 
-	if err := json.NewDecoder(r.Body).Decode(&createEntryDTO); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := decodeJSON(r, &createEntryDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := createEntryDTO.Validate(); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := validate(r, &createEntryDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -194,47 +249,31 @@ func (h *HTTPHandlers) CreateEntryHandler(w http.ResponseWriter, r *http.Request
 		createEntryDTO.PaymentMethod,
 	)
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	resp := struct {
-		Course        string `json:"course"`
-		Date          string `json:"date"`
-		UserID        int    `json:"user_id"`
-		PaymentMethod string `json:"payment_method"`
-		Status        string `json:"status"`
-	}{
-		Course:        entry.Course,
-		Date:          createEntryDTO.Date,
-		UserID:        entry.UserID,
-		PaymentMethod: entry.PaymentMethod,
-		Status:        entry.Status,
-	}
-
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
+	w.Header().Set("ETag", entryETag(entry))
+	writeJSON(w, http.StatusCreated, NewEntryDTO(entry))
 }
 
 /*
 pattern: /entry?user_id=x
 method:  GET
-info:    query params
+info:    query params, optional If-None-Match header
 
 succeed:
-  - status code: 200 OK
+  - status code: 200 OK, 304 Not Modified when If-None-Match has the ETag
   - response body: JSON with entries
 failed:
   - status code: 400, 500
-  - response body: JSON with error + time
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
 func (h *HTTPHandlers) GetEntriesHandler(w http.ResponseWriter, r *http.Request) {
 	entries, err := h.entryRepo.GetEntries(r.Context())
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -254,119 +293,190 @@ func (h *HTTPHandlers) GetEntriesHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	resp := struct {
-		Entries []entry.Entry `json:"entries"`
+		Entries []EntryDTO `json:"entries"`
 	}{
-		Entries: entries,
+		Entries: make([]EntryDTO, 0, len(entries)),
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, NewEntryDTO(e))
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+	if writeNotModified(w, r, entriesETag(entries)) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
 }
 
 /*
-pattern: /entry
+pattern: /entry/{entry_id}
+method:  GET
+info:    in pattern, only the owner or an admin,
+         optional If-None-Match header
+
+succeed:
+  - status code: 200 OK, 304 Not Modified when If-None-Match has the ETag
+  - response body: JSON of entry
+failed:
+  - status code: 400, 401, 403, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) GetEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.entryForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if writeNotModified(w, r, entryETag(entry)) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewEntryDTO(entry))
+}
+
+/*
+pattern: /entry/{entry_id}
 method:  PATCH
-info:    JSON with entry id and status
+info:    in pattern + JSON with status, admin only,
+         If-Match header with the ETag or *
 
 succeed:
   - status code: 200 OK
-  - response body: JSON with entries
+  - response body: JSON of updated entry
 failed:
-  - status code: 400, 500
-  - response body: JSON with error + time
+  - status code: 400, 422(validation), 401, 404, 412(changed meanwhile), 428(no If-Match), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
 func (h *HTTPHandlers) UpdateEntryHandler(w http.ResponseWriter, r *http.Request) {
-	var updateEntryDTO UpdateEntryDTO
+	entryID, err := idFromURLParam(r, "entry_id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&updateEntryDTO); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := updateEntryDTO.Validate(); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	var updateEntryDTO UpdateEntryDTO
+
+	if err := decodeJSON(r, &updateEntryDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	entry, err := h.entryRepo.UpdateStatusEntry(r.Context(), updateEntryDTO.ID, updateEntryDTO.Status)
-	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := validate(r, &updateEntryDTO); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	resp := struct {
-		Course        string `json:"course"`
-		Date          string `json:"date"`
-		UserID        int    `json:"user_id"`
-		PaymentMethod string `json:"payment_method"`
-		Status        string `json:"status"`
-	}{
-		Course:        entry.Course,
-		Date:          entry.Date.Format(time.DateOnly),
-		UserID:        entry.UserID,
-		PaymentMethod: entry.PaymentMethod,
-		Status:        entry.Status,
+	entry, _, err := h.entryRepo.UpdateStatusEntry(r.Context(), entryID, updateEntryDTO.Status, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+	w.Header().Set("ETag", entryETag(entry))
+	writeJSON(w, http.StatusOK, NewEntryDTO(entry))
 }
 
 /*
 pattern: /entry/{entry_id}
-method:  GET
-info:    in pattern
+method:  DELETE
+info:    in pattern, only the owner or an admin,
+         If-Match header with the ETag or *
 
 succeed:
-  - status code: 200 OK
-  - response body: JSON of updated entry
+  - status code: 204 No Content
 failed:
-  - status code: 400, 404, 500
-  - response body: JSON with error + time
+  - status code: 400, 401, 403, 404, 412(changed meanwhile), 428(no If-Match), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
-func (h *HTTPHandlers) GetEntriesByHandler(w http.ResponseWriter, r *http.Request) {
-	var updateEntryDTO UpdateEntryDTO
+func (h *HTTPHandlers) DeleteEntryHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&updateEntryDTO); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	entry, ok := h.entryForRequest(w, r)
+	if !ok {
 		return
 	}
 
-	if err := updateEntryDTO.Validate(); err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+	if err := h.entryRepo.DeleteEntry(r.Context(), entry.ID, version); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	entry, err := h.entryRepo.UpdateStatusEntry(r.Context(), updateEntryDTO.ID, updateEntryDTO.Status)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// entryForRequest loads the entry addressed by the {entry_id} path parameter
+// and checks that the caller owns it or is an admin. On failure it writes
+// the error response itself and returns false.
+func (h *HTTPHandlers) entryForRequest(w http.ResponseWriter, r *http.Request) (entry.Entry, bool) {
+	r, span := startSpan(r, "http.entryForRequest")
+	defer span.End()
+
+	entryID, err := idFromURLParam(r, "entry_id")
+	if err != nil {
+		writeError(w, r, err)
+		return entry.Entry{}, false
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return entry.Entry{}, false
+	}
+
+	e, err := h.entryRepo.GetEntryByID(r.Context(), entryID)
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
-		return
+		writeError(w, r, err)
+		return entry.Entry{}, false
 	}
 
-	resp := struct {
-		Course        string `json:"course"`
-		Date          string `json:"date"`
-		UserID        int    `json:"user_id"`
-		PaymentMethod string `json:"payment_method"`
-		Status        string `json:"status"`
-	}{
-		Course:        entry.Course,
-		Date:          entry.Date.Format(time.DateOnly),
-		UserID:        entry.UserID,
-		PaymentMethod: entry.PaymentMethod,
-		Status:        entry.Status,
+	if e.UserID == userID {
+		return e, true
+	}
+
+	isAdmin, err := h.authService.IsAdmin(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, auth.ErrInvalidToken)
+		return entry.Entry{}, false
 	}
+	if !isAdmin {
+		writeError(w, r, ErrAccessDenied)
+		return entry.Entry{}, false
+	}
+
+	return e, true
+}
+
+// currentUserID returns the ID of the authenticated caller. When it is
+// missing it writes 401 and returns false.
+func currentUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, auth.ErrInvalidToken)
+		return 0, false
+	}
+
+	return userID, true
+}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+func idFromURLParam(r *http.Request, key string) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, key))
+	if err != nil || id < 0 {
+		return 0, ErrInvalidOrEmptyID
+	}
+
+	return id, nil
 }
 
 /*
@@ -376,26 +486,22 @@ info:    in pattern
 
 succeed:
   - status code: 200 OK
-  - response body: JSON with isAdmin boolean
+  - response body: JSON with is_admin boolean
 failed:
   - status code: 400, 404, 500
-  - response body: JSON with error + time
+  - response body: JSON error envelope (code, message, fields, request_id, time)
 */
 
 func (h *HTTPHandlers) UserIsAdminHandler(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.PathValue("user_id")
-
-	userID, err := strconv.Atoi(userIDStr)
+	userID, err := idFromURLParam(r, "user_id")
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	isAdmin, err := h.authService.IsAdmin(r.Context(), userID)
 	if err != nil {
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -405,6 +511,187 @@ func (h *HTTPHandlers) UserIsAdminHandler(w http.ResponseWriter, r *http.Request
 		IsAdmin: isAdmin,
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+pattern: /user/me
+method:  GET
+info:    user from token
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of user profile
+failed:
+  - status code: 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) GetMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	usr, err := h.userRepo.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewUserDTO(usr))
+}
+
+/*
+pattern: /user/me
+method:  PATCH
+info:    JSON with profile fields to change
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of updated user profile
+failed:
+  - status code: 400, 422(validation), 401, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) UpdateMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var updateProfileDTO UpdateProfileDTO
+
+	if err := decodeJSON(r, &updateProfileDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := validate(r, &updateProfileDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	usr, err := h.userRepo.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	updateProfileDTO.Apply(&usr)
+
+	usr, err = h.userRepo.UpdateUser(r.Context(), usr)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewUserDTO(usr))
+}
+
+/*
+pattern: /user/me/password
+method:  POST
+info:    JSON with current and new password
+
+succeed:
+  - status code: 204 No Content
+failed:
+  - status code: 400, 422(validation), 401, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var changePasswordDTO ChangePasswordDTO
+
+	if err := decodeJSON(r, &changePasswordDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := validate(r, &changePasswordDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err := h.authService.ChangePassword(
+		r.Context(),
+		userID,
+		changePasswordDTO.CurrentPassword,
+		changePasswordDTO.NewPassword,
+	)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+pattern: /user/me
+method:  DELETE
+info:    JSON with password, entries of the user are deleted too
+
+succeed:
+  - status code: 204 No Content
+failed:
+  - status code: 400, 422(validation), 401, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *HTTPHandlers) DeleteMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var deleteAccountDTO DeleteAccountDTO
+
+	if err := decodeJSON(r, &deleteAccountDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := validate(r, &deleteAccountDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deleteEntries := func(ctx context.Context) error {
+		return h.deleteUserEntries(ctx, userID)
+	}
+	if err := h.authService.DeleteAccount(r.Context(), userID, deleteAccountDTO.Password, deleteEntries); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteUserEntries deletes the entries of the user. It runs before the
+// user is deleted, so a failure leaves the user to delete again rather
+// than entries of a user that is gone.
+func (h *HTTPHandlers) deleteUserEntries(ctx context.Context, userID int) error {
+	entries, err := h.entryRepo.GetEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.UserID != userID {
+			continue
+		}
+		if err := h.entryRepo.DeleteEntry(ctx, e.ID, entry.AnyVersion); err != nil && !errors.Is(err, inmem.ErrEntryNotFound) {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"practice-backend/internal/health"
+)
+
+/*
+pattern: /healthz
+method:  GET
+info:    liveness probe, the process is up
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with status
+*/
+
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: health.StatusOK,
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+pattern: /readyz
+method:  GET
+info:    readiness probe, checks the registered dependencies
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with status of every component
+failed:
+  - status code: 503 (a dependency fails or the server is shutting down)
+  - response body: JSON with status of every component
+*/
+
+func ReadinessHandler(h *health.Health) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, status, report)
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"practice-backend/internal/idempotency"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+var ErrInvalidIdempotencyKey = errors.New("idempotency key must be 1 to 255 printable characters")
+
+// replayedHeaders are the response headers stored with the body.
+var replayedHeaders = []string{"Content-Type", "Content-Language", "ETag", "Location"}
+
+// IdempotencyMiddleware makes POST requests with an Idempotency-Key header
+// safe to retry. The first response is stored per key and user for ttl and
+// replayed to retries with the same body; reusing the key for another body
+// is rejected with 422 and a retry racing the first request with 409.
+// Server errors are not stored, so the request can be retried for real.
+func IdempotencyMiddleware(store idempotency.Store, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !validIdempotencyKey(key) {
+				writeError(w, r, ErrInvalidIdempotencyKey)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+			if err != nil {
+				writeError(w, r, invalidJSON(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// anonymous keys share one scope, the fingerprint keeps others
+			// from reading a response without sending the same body
+			scope := "anon"
+			if userID, ok := userIDFromContext(r.Context()); ok {
+				scope = "user:" + strconv.Itoa(userID)
+			}
+			storeKey := scope + ":" + r.URL.Path + ":" + key
+
+			resp, err := store.Begin(r.Context(), storeKey, requestFingerprint(r, body), ttl)
+			switch {
+			case errors.Is(err, idempotency.ErrInProgress), errors.Is(err, idempotency.ErrKeyReused):
+				writeError(w, r, err)
+				return
+			case err != nil:
+				LoggerFromContext(r.Context()).Error("idempotency store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			case resp != nil:
+				replayResponse(w, resp)
+				return
+			}
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			completed := false
+			defer func() {
+				if !completed {
+					store.Release(r.Context(), storeKey)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			header := make(http.Header)
+			for _, name := range replayedHeaders {
+				if v := ww.Header().Values(name); len(v) > 0 {
+					header[name] = v
+				}
+			}
+
+			err = store.Complete(r.Context(), storeKey, idempotency.Response{
+				StatusCode: status,
+				Header:     header,
+				Body:       buf.Bytes(),
+			})
+			if err != nil {
+				LoggerFromContext(r.Context()).Error("idempotency store failed", "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+
+	for _, c := range key {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestFingerprint tells retries from other requests sent with the same key.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replayResponse(w http.ResponseWriter, resp *idempotency.Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/idempotency"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if strings.Contains(r.URL.Path, "fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]int{"id": calls})
+	})
+	handler := IdempotencyMiddleware(idempotency.NewMemoryStore(), time.Hour)(next)
+
+	testCases := []struct {
+		title        string
+		path         string
+		key          string
+		body         string
+		wantStatus   int
+		wantBody     string
+		wantReplayed bool
+		wantCalls    int
+	}{
+		{title: "happy: without key", path: "/api/entry", body: `{}`, wantStatus: http.StatusCreated, wantBody: `{"id":1}`, wantCalls: 1},
+		{title: "happy: first request", path: "/api/entry", key: "a", body: `{}`, wantStatus: http.StatusCreated, wantBody: `{"id":2}`, wantCalls: 2},
+		{title: "happy: retry is replayed", path: "/api/entry", key: "a", body: `{}`, wantStatus: http.StatusCreated, wantBody: `{"id":2}`, wantReplayed: true, wantCalls: 2},
+		{title: "happy: same key on another route", path: "/api/user/register", key: "a", body: `{}`, wantStatus: http.StatusCreated, wantBody: `{"id":3}`, wantCalls: 3},
+		{title: "sad: key reused with another body", path: "/api/entry", key: "a", body: `{"course":"x"}`, wantStatus: http.StatusUnprocessableEntity, wantCalls: 3},
+		{title: "sad: invalid key", path: "/api/entry", key: "with space", body: `{}`, wantStatus: http.StatusBadRequest, wantCalls: 3},
+		{title: "sad: server error", path: "/api/fail", key: "b", body: `{}`, wantStatus: http.StatusInternalServerError, wantCalls: 4},
+		{title: "happy: server error is not stored", path: "/api/fail", key: "b", body: `{}`, wantStatus: http.StatusInternalServerError, wantCalls: 5},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.key != "" {
+			r.Header.Set(IdempotencyKeyHeader, tc.key)
+		}
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, tc.wantStatus, w.Code, tc.title)
+		if tc.wantBody != "" {
+			assert.JSONEq(t, tc.wantBody, w.Body.String(), tc.title)
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), tc.title)
+		}
+		assert.Equal(t, tc.wantReplayed, w.Header().Get(IdempotentReplayedHeader) == "true", tc.title)
+		assert.Equal(t, tc.wantCalls, calls, tc.title)
+	}
+}
@@ -1,39 +1,340 @@
 package http
 
 import (
-	"errors"
-	"fmt"
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"practice-backend/internal/i18n"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/models/user"
 	"practice-backend/internal/services/auth"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+type ctxKey int
+
+const (
+	userIDCtxKey ctxKey = iota
+	requestIDCtxKey
+	loggerCtxKey
+	accessLogCtxKey
+	languageCtxKey
+)
+
+// accessLog collects what inner middlewares learn about the request,
+// so AccessLogMiddleware can write it once the request is served.
+type accessLog struct {
+	userID *int
+}
+
+// userIDFromContext returns the ID of the authenticated user put into
+// the context by AuthMiddleware or AdminMiddleware.
+func userIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDCtxKey).(int)
+	return userID, ok
+}
+
+// RequestIDFromContext returns the ID given to the request by
+// RequestIDMiddleware.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDCtxKey).(string)
+	return requestID
+}
+
+// LoggerFromContext returns the request logger, which carries the request ID
+// and the user ID once known. Outside of a request it is slog.Default().
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// LanguageFromContext returns the language negotiated by
+// LanguageMiddleware, i18n.Default outside of a request.
+func LanguageFromContext(ctx context.Context) i18n.Language {
+	if lang, ok := ctx.Value(languageCtxKey).(i18n.Language); ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+// withUserID stores the authenticated user in the request context,
+// the request logger and the access log.
+func withUserID(r *http.Request, userID int) *http.Request {
+	ctx := context.WithValue(r.Context(), userIDCtxKey, userID)
+	ctx = context.WithValue(ctx, loggerCtxKey, LoggerFromContext(ctx).With("user_id", userID))
+
+	if info, ok := ctx.Value(accessLogCtxKey).(*accessLog); ok {
+		info.userID = &userID
+	}
+
+	return r.WithContext(ctx)
+}
+
+// LanguageMiddleware picks the language of the response: the language
+// of the user's profile when the request has a valid token, otherwise the
+// best match of Accept-Language.
+func LanguageMiddleware(userRepo user.UserRepo) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+			if preferred, ok := preferredLanguage(r, userRepo); ok {
+				lang = preferred
+			}
+
+			w.Header().Add("Vary", "Accept-Language")
+			w.Header().Set("Content-Language", string(lang))
+
+			ctx := context.WithValue(r.Context(), languageCtxKey, lang)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func preferredLanguage(r *http.Request, userRepo user.UserRepo) (i18n.Language, bool) {
+	if r.Header.Get("Authorization") == "" {
+		return "", false
+	}
+
+	token, err := validateToken(r)
+	if err != nil {
+		return "", false
+	}
+
+	userID, err := userIDFromToken(token)
+	if err != nil {
+		return "", false
+	}
+
+	usr, err := userRepo.GetUserByID(r.Context(), userID)
+	if err != nil || usr.Language == "" {
+		return "", false
+	}
+
+	lang, err := i18n.Parse(usr.Language)
+	if err != nil {
+		return "", false
+	}
+
+	return lang, true
+}
+
+// RequestIDMiddleware takes the request ID from the X-Request-ID header or
+// generates a new one, and sends it back in the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := validateToken(w, r)
-		if err != nil {
-			errDTO := NewErrorDTO(auth.ErrInvalidToken)
-			http.Error(w, errDTO.String(), http.StatusUnauthorized)
-			return
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
 		}
 
-		fmt.Printf("Token verified successfully. Claims: %+v\\n", token.Claims)
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDCtxKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && !strings.ContainsRune("-_.:", c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLogMiddleware puts the request logger into the context and writes
+// one log record per request. It must run after RequestIDMiddleware.
+func AccessLogMiddleware(logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			reqLogger := logger.With("request_id", RequestIDFromContext(r.Context()))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				reqLogger = reqLogger.With("trace_id", sc.TraceID().String())
+			}
+			info := &accessLog{}
+
+			ctx := context.WithValue(r.Context(), loggerCtxKey, reqLogger)
+			ctx = context.WithValue(ctx, accessLogCtxKey, info)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", routePattern(r)),
+				slog.String("path", maskedPath(r)),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", ww.BytesWritten()),
+			}
+			if info.userID != nil {
+				attrs = append(attrs, slog.Int("user_id", *info.userID))
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			reqLogger.LogAttrs(r.Context(), level, "request served", attrs...)
+		})
+	}
+}
+
+var tracer = otel.Tracer("practice-backend/internal/http")
+
+// startSpan starts a span for a step of the handler, like decoding the
+// body or checking ownership. The returned request carries the span, so
+// the calls made in the step are nested in it.
+func startSpan(r *http.Request, name string) (*http.Request, trace.Span) {
+	ctx, span := tracer.Start(r.Context(), name)
+	return r.WithContext(ctx), span
+}
+
+// TracingMiddleware starts a server span for the request, continuing the
+// trace from the traceparent header. The span is named by the chi route
+// pattern once the request is routed. The handlers add spans for their
+// steps with startSpan.
+func TracingMiddleware(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		next.ServeHTTP(w, r)
+
+		pattern := routePattern(r)
+
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + pattern)
+		span.SetAttributes(semconv.HTTPRoute(pattern), semconv.URLPath(maskedPath(r)))
 	})
+
+	return otelhttp.NewHandler(named, "http.server")
+}
+
+// MetricsMiddleware counts requests and their latency by chi route pattern.
+func MetricsMiddleware(m *metrics.Metrics) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			m.ObserveHTTPRequest(r.Method, routePattern(r), status, time.Since(start))
+		})
+	}
+}
+
+// secretParams are the route parameters that are credentials, e.g. the
+// token of a calendar feed.
+var secretParams = []string{"token"}
+
+// maskedPath returns the request path with the values of secret route
+// parameters masked, for logs and traces.
+func maskedPath(r *http.Request) string {
+	path := r.URL.Path
+
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return path
+	}
+	for _, name := range secretParams {
+		if value := rctx.URLParam(name); value != "" {
+			path = strings.Replace(path, value, "***", 1)
+		}
+	}
+
+	return path
 }
 
-func validateToken(w http.ResponseWriter, r *http.Request) (*jwt.Token, error) {
+// routePattern returns the chi route pattern, like /api/entry/{entry_id},
+// so requests to the same route are grouped regardless of IDs.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
+
+// AuthMiddleware lets through requests with a valid token of a user who
+// still exists and is not deactivated, so deactivation and deletion take
+// effect before the tokens expire.
+func AuthMiddleware(authService Auth) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := validateToken(r)
+			if err != nil {
+				writeError(w, r, auth.ErrInvalidToken)
+				return
+			}
+
+			userID, err := userIDFromToken(token)
+			if err != nil {
+				writeError(w, r, auth.ErrInvalidToken)
+				return
+			}
+
+			if err := authService.Authenticate(r.Context(), userID); err != nil {
+				writeError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, withUserID(r, userID))
+		})
+	}
+}
+
+func validateToken(r *http.Request) (*jwt.Token, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		return nil, auth.ErrInvalidToken
 	}
 
-	tokenString := strings.Split(authHeader, " ")[1]
-
-	if tokenString == "" {
+	tokenString, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok || tokenString == "" {
 		return nil, auth.ErrInvalidToken
 	}
 
@@ -45,6 +346,36 @@ func validateToken(w http.ResponseWriter, r *http.Request) (*jwt.Token, error) {
 	return token, nil
 }
 
+func userIDFromToken(token *jwt.Token) (int, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, auth.ErrInvalidToken
+	}
+
+	userID, ok := claims["uid"].(float64)
+	if !ok {
+		return 0, ErrInvalidOrEmptyID
+	}
+
+	return int(userID), nil
+}
+
+// authTimeFromToken returns when the user logged in for the token. Tokens
+// from before the claim was added have none and can't be refreshed.
+func authTimeFromToken(token *jwt.Token) (time.Time, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return time.Time{}, auth.ErrInvalidToken
+	}
+
+	authTime, ok := claims["auth_time"].(float64)
+	if !ok {
+		return time.Time{}, auth.ErrInvalidToken
+	}
+
+	return time.Unix(int64(authTime), 0), nil
+}
+
 func verifyToken(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
 		return []byte("TEST_SECRET"), nil
@@ -61,61 +392,33 @@ func verifyToken(tokenString string) (*jwt.Token, error) {
 	return token, nil
 }
 
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Println(r.RequestURI)
-		next.ServeHTTP(w, r)
-	})
-}
-
 func AdminMiddleware(authService Auth) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token, err := validateToken(w, r)
+			token, err := validateToken(r)
 			if err != nil {
-				errDTO := NewErrorDTO(auth.ErrInvalidToken)
-				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+				writeError(w, r, auth.ErrInvalidToken)
 				return
 			}
 
-			claims := token.Claims.(jwt.MapClaims)
-
-			userID, ok := claims["uid"].(float64)
-			if !ok {
-				errDTO := NewErrorDTO(ErrInvalidOrEmptyID)
-				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+			userID, err := userIDFromToken(token)
+			if err != nil {
+				writeError(w, r, auth.ErrInvalidToken)
 				return
 			}
 
-			isAdmin, err := authService.IsAdmin(r.Context(), int(userID))
+			isAdmin, err := authService.IsAdmin(r.Context(), userID)
 			if err != nil {
-				errDTO := NewErrorDTO(err)
-				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+				writeError(w, r, auth.ErrInvalidToken)
 				return
 			}
 
 			if !isAdmin {
-				errDTO := NewErrorDTO(errors.New("user is not admin"))
-				http.Error(w, errDTO.String(), http.StatusBadRequest)
+				writeError(w, r, ErrNotAdmin)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withUserID(r, userID))
 		})
 	}
 }
-
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*") // или "http://localhost:5173"
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
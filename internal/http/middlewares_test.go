@@ -0,0 +1,122 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAccessLogMasksSecrets(t *testing.T) {
+	ctx := t.Context()
+	storage := inmem.NewStorage()
+	authService := auth.NewAuth(storage, nil)
+
+	u, err := storage.CreateUser(ctx, "ivan", "hash", "Ivan", "", "", "", "", false)
+	require.NoError(t, err)
+	_, err = storage.SetCalendarToken(ctx, u.ID, "SECRETTOKEN")
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	server := NewHTTPServer(*NewHTTPHandlers(storage, storage, authService), ServerConfig{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+
+	testCases := []struct {
+		title      string
+		path       string
+		wantStatus int
+		wantPath   string
+	}{
+		{title: "happy: feed", path: "/api/calendar/SECRETTOKEN.ics", wantStatus: http.StatusOK, wantPath: "path=/api/calendar/***.ics"},
+		{title: "sad: unknown token", path: "/api/calendar/SECRETTOKENX.ics", wantStatus: http.StatusNotFound, wantPath: "path=/api/calendar/***.ics"},
+		{title: "happy: other routes keep the path", path: "/api/entry/7", wantStatus: http.StatusUnauthorized, wantPath: "path=/api/entry/7"},
+	}
+
+	for _, tc := range testCases {
+		logs.Reset()
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		assert.Equal(t, tc.wantStatus, rec.Code, tc.title)
+		assert.Contains(t, logs.String(), tc.wantPath, tc.title)
+		assert.NotContains(t, logs.String(), "SECRETTOKEN", tc.title)
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	storage := inmem.NewStorage()
+	m := metrics.New()
+	server := NewHTTPServer(*NewHTTPHandlers(storage, storage, auth.NewAuth(storage, nil)), ServerConfig{
+		Metrics: m,
+	})
+
+	testCases := []struct {
+		title      string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{title: "happy: route with an ID", method: http.MethodGet, path: "/api/entry/7", wantStatus: http.StatusUnauthorized},
+		{title: "happy: same route, other ID", method: http.MethodGet, path: "/api/entry/8", wantStatus: http.StatusUnauthorized},
+		{title: "happy: probe", method: http.MethodGet, path: "/healthz", wantStatus: http.StatusOK},
+		{title: "sad: unknown path", method: http.MethodGet, path: "/nope", wantStatus: http.StatusNotFound},
+		{title: "sad: metrics are not public", method: http.MethodGet, path: "/metrics", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.wantStatus, rec.Code, tc.title)
+	}
+
+	expected := `
+# HELP practice_backend_http_requests_total HTTP requests by method, chi route pattern and status.
+# TYPE practice_backend_http_requests_total counter
+practice_backend_http_requests_total{method="GET",route="/api/entry/{entry_id}",status="401"} 2
+practice_backend_http_requests_total{method="GET",route="/healthz",status="200"} 1
+practice_backend_http_requests_total{method="GET",route="unmatched",status="404"} 2
+`
+	err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "practice_backend_http_requests_total")
+	assert.NoError(t, err)
+}
+
+func TestTracingHandlerSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	global := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(global) })
+
+	storage := inmem.NewStorage()
+	server := NewHTTPServer(*NewHTTPHandlers(storage, storage, auth.NewAuth(storage, nil)), ServerConfig{})
+
+	body := strings.NewReader(`{"login":"ivan","password":"secret","name":"Ivan","surname":"Ivanov","patronymic":"Ivanovich","phone":"89991234567","email":"ivan@example.com"}`)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/user/register", body))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root, ok := spans["POST /api/user/register"]
+	require.True(t, ok, "the request span is named by the route")
+	for _, name := range []string{"http.decodeJSON", "http.validate", "auth.Register"} {
+		span, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(), name)
+		}
+	}
+}
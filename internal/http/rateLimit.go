@@ -0,0 +1,135 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/ratelimit"
+	"strconv"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+var ErrRateLimited = errors.New("too many requests")
+
+// KeyFunc returns who a request is counted for, false when it can't tell.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// KeyByIP counts requests by the client address.
+func KeyByIP(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host, true
+}
+
+// KeyByUser counts requests by the authenticated user, also before
+// AuthMiddleware has run.
+func KeyByUser(r *http.Request) (string, bool) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		token, err := validateToken(r)
+		if err != nil {
+			return "", false
+		}
+
+		if userID, err = userIDFromToken(token); err != nil {
+			return "", false
+		}
+	}
+
+	return "user:" + strconv.Itoa(userID), true
+}
+
+// KeyByAPIKey counts requests by the X-API-Key header. Only keys accepted
+// by valid are used, otherwise anyone could dodge the limit with random keys.
+func KeyByAPIKey(valid func(key string) bool) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" || !valid(key) {
+			return "", false
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:8]), true
+	}
+}
+
+// FirstKey uses the first of keys that knows the request.
+func FirstKey(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		for _, key := range keys {
+			if k, ok := key(r); ok {
+				return k, true
+			}
+		}
+
+		return "", false
+	}
+}
+
+// KeyByPrincipal counts authenticated requests by user and the others by IP.
+var KeyByPrincipal = FirstKey(KeyByUser, KeyByIP)
+
+// RateLimitPolicy is the limit of a route group.
+type RateLimitPolicy struct {
+	// Name separates the buckets of groups and labels the metric
+	Name  string
+	Limit ratelimit.Limit
+	Key   KeyFunc
+}
+
+// RateLimitMiddleware answers 429 with Retry-After once the client has
+// spent its limit. Every answer carries the RateLimit-* headers. When the
+// store fails the request is let through.
+func RateLimitMiddleware(store ratelimit.Store, policy RateLimitPolicy, m *metrics.Metrics) func(next http.Handler) http.Handler {
+	if policy.Limit.IsZero() {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	policyHeader := strconv.Itoa(policy.Limit.Requests) + ";w=" + strconv.Itoa(int(policy.Limit.Per.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := policy.Key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := store.Take(r.Context(), policy.Name+":"+key, policy.Limit)
+			if err != nil {
+				LoggerFromContext(r.Context()).Error("rate limit store failed", "policy", policy.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", policyHeader)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.ResetAfter))
+
+			if !res.Allowed {
+				m.IncRateLimited(policy.Name)
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				writeError(w, r, ErrRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	policy := RateLimitPolicy{
+		Name:  "auth",
+		Limit: ratelimit.Limit{Requests: 2, Per: time.Minute},
+		Key:   KeyByIP,
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := RateLimitMiddleware(store, policy, nil)(next)
+
+	testCases := []struct {
+		title          string
+		remoteAddr     string
+		wantStatus     int
+		wantRemaining  string
+		wantRetryAfter string
+	}{
+		{title: "happy: first request", remoteAddr: "10.0.0.1:1000", wantStatus: http.StatusOK, wantRemaining: "1"},
+		{title: "happy: other port of the same client", remoteAddr: "10.0.0.1:1001", wantStatus: http.StatusOK, wantRemaining: "0"},
+		{title: "sad: limit is spent", remoteAddr: "10.0.0.1:1002", wantStatus: http.StatusTooManyRequests, wantRemaining: "0", wantRetryAfter: "30"},
+		{title: "happy: other client", remoteAddr: "10.0.0.2:1000", wantStatus: http.StatusOK, wantRemaining: "1"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/login", nil)
+		r.RemoteAddr = tc.remoteAddr
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, tc.wantStatus, w.Code, tc.title)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"), tc.title)
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"), tc.title)
+		assert.Equal(t, tc.wantRemaining, w.Header().Get("RateLimit-Remaining"), tc.title)
+		assert.Equal(t, tc.wantRetryAfter, w.Header().Get("Retry-After"), tc.title)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	byKey := KeyByAPIKey(func(key string) bool { return key == "valid" })
+	key := FirstKey(byKey, KeyByIP)
+
+	testCases := []struct {
+		title   string
+		apiKey  string
+		wantKey string
+	}{
+		{title: "happy: no api key", wantKey: "ip:192.0.2.1"},
+		{title: "happy: valid api key", apiKey: "valid", wantKey: "key:ec654fac9599f62e"},
+		{title: "sad: unknown api key", apiKey: "random", wantKey: "ip:192.0.2.1"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/api/entry", nil)
+		if tc.apiKey != "" {
+			r.Header.Set(APIKeyHeader, tc.apiKey)
+		}
+
+		got, ok := key(r)
+
+		assert.True(t, ok, tc.title)
+		assert.Equal(t, tc.wantKey, got, tc.title)
+	}
+}
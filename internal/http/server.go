@@ -1,65 +1,286 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"practice-backend/internal/events"
+	"practice-backend/internal/health"
+	"practice-backend/internal/idempotency"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/models/webhook"
+	"practice-backend/internal/ratelimit"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+const defaultIdempotencyTTL = 24 * time.Hour
+
 type HTTPServer struct {
 	httpHandlers HTTPHandlers
-	port         int
-	host         string
+	cfg          ServerConfig
+	server       *http.Server
+	// metricsServer serves /metrics apart from the API, nil without
+	// MetricsAddr
+	metricsServer *http.Server
+	// streamsDone is closed on shutdown to end event streams, which
+	// would keep Shutdown waiting otherwise
+	streamsDone chan struct{}
+}
+
+type ServerConfig struct {
+	Host   string
+	Port   int
+	Logger *slog.Logger
+	// Metrics record the requests when set
+	Metrics *metrics.Metrics
+	// MetricsAddr is the host:port /metrics is served on, a listener of
+	// its own so the metrics aren't public with the API. Without it the
+	// metrics are not served.
+	MetricsAddr string
+	// Health backs /readyz, without it only /healthz is served
+	Health *health.Health
+	// CORS is the cross-origin policy of /api
+	CORS CORSConfig
+	// RateLimits are the limits of the route groups
+	RateLimits RateLimits
+	// RateLimitStore keeps the rate limit buckets, a MemoryStore by default
+	RateLimitStore ratelimit.Store
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key are replayed, a day by default
+	IdempotencyTTL time.Duration
+	// IdempotencyStore keeps the responses, a MemoryStore by default
+	IdempotencyStore idempotency.Store
+	// Events feeds the event streams and the WebSocket, a bus without
+	// publishers by default
+	Events *events.Bus
+	// SSEHeartbeat is how often idle event streams get a comment line
+	SSEHeartbeat time.Duration
+	// Webhooks backs /api/admin/webhooks, without it the routes are not
+	// served
+	Webhooks webhook.WebhookRepo
+	// Calendar places the courses in the calendar feeds
+	Calendar CalendarConfig
 }
 
-func NewHTTPServer(httpHandlers HTTPHandlers, port int, host string) *HTTPServer {
-	return &HTTPServer{
+// RateLimits of the route groups, zero limits are off.
+type RateLimits struct {
+	// API is shared by all /api routes, by user or IP
+	API ratelimit.Limit
+	// Auth is for register and login, by IP
+	Auth ratelimit.Limit
+	// Entries is for creating entries, by user
+	Entries ratelimit.Limit
+}
+
+func NewHTTPServer(httpHandlers HTTPHandlers, cfg ServerConfig) *HTTPServer {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.RateLimitStore == nil {
+		cfg.RateLimitStore = ratelimit.NewMemoryStore()
+	}
+	if cfg.IdempotencyTTL == 0 {
+		cfg.IdempotencyTTL = defaultIdempotencyTTL
+	}
+	if cfg.IdempotencyStore == nil {
+		cfg.IdempotencyStore = idempotency.NewMemoryStore()
+	}
+	if cfg.Events == nil {
+		cfg.Events = events.NewBus(0)
+	}
+	if cfg.SSEHeartbeat == 0 {
+		cfg.SSEHeartbeat = DefaultSSEHeartbeat
+	}
+
+	h := &HTTPServer{
 		httpHandlers: httpHandlers,
-		port:         port,
-		host:         host,
+		cfg:          cfg,
+		streamsDone:  make(chan struct{}),
+	}
+	h.server = &http.Server{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:  h.configureRouter(),
+		ErrorLog: slog.NewLogLogger(cfg.Logger.Handler(), slog.LevelError),
 	}
+	h.server.RegisterOnShutdown(func() { close(h.streamsDone) })
+
+	if cfg.Metrics != nil && cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", cfg.Metrics.Handler())
+		h.metricsServer = &http.Server{
+			Addr:     cfg.MetricsAddr,
+			Handler:  mux,
+			ErrorLog: h.server.ErrorLog,
+		}
+	}
+
+	return h
 }
 
-func (h *HTTPServer) Start() error {
-	router := h.configureRouter()
+// servers are the API server and the metrics server, when there is one.
+func (h *HTTPServer) servers() []*http.Server {
+	if h.metricsServer == nil {
+		return []*http.Server{h.server}
+	}
 
-	socket := fmt.Sprintf("%s:%d", h.host, h.port)
+	return []*http.Server{h.server, h.metricsServer}
+}
 
-	if err := http.ListenAndServe(socket, router); err != nil {
-		if !errors.Is(err, http.ErrServerClosed) {
+// Start serves requests until Shutdown is called. All addresses are
+// listened on before anything is served, so a taken one fails Start
+// right away.
+func (h *HTTPServer) Start() error {
+	servers := h.servers()
+
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, s := range servers {
+		l, err := net.Listen("tcp", s.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
 			return err
 		}
+		listeners = append(listeners, l)
+	}
+
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Go(func() {
+			if err := s.Serve(listeners[i]); !errors.Is(err, http.ErrServerClosed) {
+				errs[i] = err
+			}
+		})
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Handler returns the router, e.g. to serve it with httptest.
+func (h *HTTPServer) Handler() http.Handler {
+	return h.server.Handler
+}
+
+// Shutdown stops accepting connections and waits for active requests.
+func (h *HTTPServer) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, s := range h.servers() {
+		errs = append(errs, s.Shutdown(ctx))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func (h *HTTPServer) configureRouter() http.Handler {
 	router := chi.NewRouter()
+	router.Use(TracingMiddleware)
+	router.Use(RequestIDMiddleware)
+	router.Use(AccessLogMiddleware(h.cfg.Logger))
+	router.Use(LanguageMiddleware(h.httpHandlers.userRepo))
+
+	if h.cfg.Metrics != nil {
+		router.Use(MetricsMiddleware(h.cfg.Metrics))
+	}
+
+	router.NotFound(notFoundHandler)
+	router.MethodNotAllowed(methodNotAllowedHandler)
+
+	router.Get("/healthz", LivenessHandler)
+	if h.cfg.Health != nil {
+		router.Get("/readyz", ReadinessHandler(h.cfg.Health))
+	}
 
 	router.Route("/api", func(r chi.Router) {
-		r.Use(LoggingMiddleware)
-		r.Use(CorsMiddleware)
+		r.Use(CorsMiddleware(h.cfg.CORS))
+		r.Use(h.rateLimit("api", h.cfg.RateLimits.API, KeyByPrincipal))
 
-		r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
-			w.Write([]byte("HelloWorld"))
-		})
+		authenticated := AuthMiddleware(h.httpHandlers.authService)
+
+		r.Get("/openapi.json", OpenAPIHandler)
+		r.Get("/docs", DocsHandler)
 
-		r.Post("/user/register", h.httpHandlers.RegisterHandler)
-		r.Post("/user/login", h.httpHandlers.LoginHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(h.rateLimit("auth", h.cfg.RateLimits.Auth, KeyByIP))
+
+			r.With(h.idempotent).Post("/user/register", h.httpHandlers.RegisterHandler)
+			r.Post("/user/login", h.httpHandlers.LoginHandler)
+		})
 		r.Get("/user/{user_id}", h.httpHandlers.UserIsAdminHandler)
+		r.With(authenticated).Post("/user/token/refresh", h.httpHandlers.RefreshTokenHandler)
+
+		r.With(authenticated).Get("/user/me", h.httpHandlers.GetMeHandler)
+		r.With(authenticated).Patch("/user/me", h.httpHandlers.UpdateMeHandler)
+		r.With(authenticated).Delete("/user/me", h.httpHandlers.DeleteMeHandler)
+		r.With(authenticated).Post("/user/me/password", h.httpHandlers.ChangePasswordHandler)
 
-		r.With(AuthMiddleware).Post("/entry", h.httpHandlers.CreateEntryHandler)
-		r.With(AuthMiddleware).Get("/entry", h.httpHandlers.GetEntriesHandler)
-		// r.With(AuthMiddleware).Patch("/entry", h.httpHandlers.UpdateEntryHandler)
-		r.With(AdminMiddleware(h.httpHandlers.authService)).Patch("/entry", h.httpHandlers.UpdateEntryHandler)
+		calendarHandlers := NewCalendarHandlers(&h.httpHandlers, h.cfg.Calendar)
+		r.With(authenticated).Get("/user/me/calendar", calendarHandlers.GetCalendarHandler)
+		r.With(authenticated).Post("/user/me/calendar", calendarHandlers.CreateCalendarHandler)
+		r.With(authenticated).Delete("/user/me/calendar", calendarHandlers.DeleteCalendarHandler)
+		r.Get("/calendar/{token}.ics", calendarHandlers.FeedHandler)
+		r.With(authenticated).Get("/entry/{entry_id}/calendar.ics", calendarHandlers.EntryCalendarHandler)
+
+		r.With(authenticated, h.rateLimit("entries", h.cfg.RateLimits.Entries, KeyByUser), h.idempotent).Post("/entry", h.httpHandlers.CreateEntryHandler)
+		r.With(authenticated).Get("/entry", h.httpHandlers.GetEntriesHandler)
+		r.With(authenticated).Get("/entry/events", EntryEventsHandler(h.cfg.Events, h.httpHandlers.authService, h.cfg.SSEHeartbeat, h.streamsDone))
+		r.With(authenticated).Get("/entry/{entry_id}", h.httpHandlers.GetEntryHandler)
+		r.With(AdminMiddleware(h.httpHandlers.authService)).Patch("/entry/{entry_id}", h.httpHandlers.UpdateEntryHandler)
+		r.With(authenticated).Delete("/entry/{entry_id}", h.httpHandlers.DeleteEntryHandler)
+
+		r.With(queryTokenMiddleware, authenticated).Get("/ws", WebSocketHandler(h.cfg.Events, h.httpHandlers.authService, h.cfg.CORS, h.streamsDone))
 
 		r.With(AdminMiddleware(h.httpHandlers.authService)).Get("/admin", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("ADMIN WRITE"))
 		})
+
+		r.Route("/admin/users", func(r chi.Router) {
+			r.Use(AdminMiddleware(h.httpHandlers.authService))
+
+			r.Get("/", h.httpHandlers.ListUsersHandler)
+			r.Get("/{user_id}", h.httpHandlers.GetUserHandler)
+			r.Delete("/{user_id}", h.httpHandlers.DeleteUserHandler)
+			r.Put("/{user_id}/admin", h.httpHandlers.GrantAdminHandler)
+			r.Delete("/{user_id}/admin", h.httpHandlers.RevokeAdminHandler)
+			r.Post("/{user_id}/deactivate", h.httpHandlers.DeactivateUserHandler)
+			r.Post("/{user_id}/reactivate", h.httpHandlers.ReactivateUserHandler)
+		})
+
+		if h.cfg.Webhooks != nil {
+			webhookHandlers := NewWebhookHandlers(h.cfg.Webhooks)
+
+			r.Route("/admin/webhooks", func(r chi.Router) {
+				r.Use(AdminMiddleware(h.httpHandlers.authService))
+
+				r.Get("/", webhookHandlers.ListWebhooksHandler)
+				r.Post("/", webhookHandlers.CreateWebhookHandler)
+				r.Get("/dead-letters", webhookHandlers.ListDeadLettersHandler)
+				r.Get("/{webhook_id}", webhookHandlers.GetWebhookHandler)
+				r.Delete("/{webhook_id}", webhookHandlers.DeleteWebhookHandler)
+				r.Get("/{webhook_id}/deliveries", webhookHandlers.ListDeliveriesHandler)
+				r.Post("/{webhook_id}/deliveries/{delivery_id}/retry", webhookHandlers.RetryDeliveryHandler)
+			})
+		}
 	})
 
 	return router
 }
+
+func (h *HTTPServer) rateLimit(name string, limit ratelimit.Limit, key KeyFunc) func(next http.Handler) http.Handler {
+	return RateLimitMiddleware(h.cfg.RateLimitStore, RateLimitPolicy{
+		Name:  name,
+		Limit: limit,
+		Key:   key,
+	}, h.cfg.Metrics)
+}
+
+func (h *HTTPServer) idempotent(next http.Handler) http.Handler {
+	return IdempotencyMiddleware(h.cfg.IdempotencyStore, h.cfg.IdempotencyTTL)(next)
+}
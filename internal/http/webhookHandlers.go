@@ -0,0 +1,309 @@
+package http
+
+import (
+	"cmp"
+	"net/http"
+	"practice-backend/internal/models/webhook"
+	"practice-backend/internal/storage/inmem"
+	"practice-backend/internal/webhooks"
+	"slices"
+	"time"
+)
+
+// WebhookHandlers manage the webhook subscriptions and their deliveries.
+// They are served to admins only.
+type WebhookHandlers struct {
+	repo webhook.WebhookRepo
+}
+
+func NewWebhookHandlers(repo webhook.WebhookRepo) *WebhookHandlers {
+	return &WebhookHandlers{
+		repo: repo,
+	}
+}
+
+/*
+pattern: /admin/webhooks
+method:  GET
+info:    -
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with webhooks, without their secrets
+failed:
+  - status code: 401, 403, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.repo.GetWebhooks(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := WebhookListDTO{
+		Webhooks: make([]WebhookDTO, 0, len(hooks)),
+	}
+	for _, hook := range hooks {
+		resp.Webhooks = append(resp.Webhooks, NewWebhookDTO(hook))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+pattern: /admin/webhooks
+method:  POST
+info:    JSON with url, events and an optional secret of 16+ characters,
+         a secret is generated when it is empty
+
+succeed:
+  - status code: 201 Created
+  - response body: JSON of created webhook with its secret
+failed:
+  - status code: 400, 401, 403, 422(validation), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var createWebhookDTO CreateWebhookDTO
+
+	if err := decodeJSON(r, &createWebhookDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := validate(r, &createWebhookDTO); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	secret := createWebhookDTO.Secret
+	if secret == "" {
+		secret = webhooks.NewSecret()
+	}
+
+	events := slices.Compact(slices.Sorted(slices.Values(createWebhookDTO.Events)))
+
+	hook, err := h.repo.CreateWebhook(r.Context(), createWebhookDTO.URL, events, secret)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	LoggerFromContext(r.Context()).Info("webhook created", "webhook_id", hook.ID, "events", hook.Events)
+
+	resp := NewWebhookDTO(hook)
+	resp.Secret = hook.Secret
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+/*
+pattern: /admin/webhooks/{webhook_id}
+method:  GET
+info:    in pattern
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of webhook, without its secret
+failed:
+  - status code: 400, 401, 403, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.webhookForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewWebhookDTO(hook))
+}
+
+/*
+pattern: /admin/webhooks/{webhook_id}
+method:  DELETE
+info:    in pattern, pending deliveries of the webhook are dropped
+
+succeed:
+  - status code: 204 No Content
+failed:
+  - status code: 400, 401, 403, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := idFromURLParam(r, "webhook_id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.repo.DeleteWebhook(r.Context(), webhookID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	LoggerFromContext(r.Context()).Info("webhook deleted", "webhook_id", webhookID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+pattern: /admin/webhooks/{webhook_id}/deliveries?status=dead&limit=20&offset=0
+method:  GET
+info:    in pattern, query params, status is pending, delivered or dead
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with deliveries page, newest first, and total count
+failed:
+  - status code: 400, 401, 403, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) ListDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.webhookForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !validDeliveryStatus(status) {
+		writeError(w, r, ErrInvalidDeliveryStatus)
+		return
+	}
+
+	h.writeDeliveries(w, r, hook.ID, status)
+}
+
+/*
+pattern: /admin/webhooks/dead-letters?limit=20&offset=0
+method:  GET
+info:    query params
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON with page of dead deliveries of all webhooks,
+    newest first, and total count
+failed:
+  - status code: 400, 401, 403, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) ListDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeDeliveries(w, r, -1, webhook.StatusDead)
+}
+
+/*
+pattern: /admin/webhooks/{webhook_id}/deliveries/{delivery_id}/retry
+method:  POST
+info:    in pattern, the delivery is sent again with a fresh set of attempts
+
+succeed:
+  - status code: 200 OK
+  - response body: JSON of pending delivery
+failed:
+  - status code: 400, 401, 403, 404, 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+func (h *WebhookHandlers) RetryDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := idFromURLParam(r, "webhook_id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deliveryID, err := idFromURLParam(r, "delivery_id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	delivery, err := h.repo.GetDeliveryByID(r.Context(), deliveryID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if delivery.WebhookID != webhookID {
+		writeError(w, r, inmem.ErrDeliveryNotFound)
+		return
+	}
+
+	delivery.Status = webhook.StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+
+	delivery, err = h.repo.UpdateDelivery(r.Context(), delivery)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	LoggerFromContext(r.Context()).Info("webhook delivery retried", "webhook_id", webhookID, "delivery_id", deliveryID)
+
+	writeJSON(w, http.StatusOK, NewDeliveryDTO(delivery))
+}
+
+// writeDeliveries answers with a page of the deliveries of the webhook,
+// of all webhooks when webhookID is negative, with the status if set.
+func (h *WebhookHandlers) writeDeliveries(w http.ResponseWriter, r *http.Request, webhookID int, status string) {
+	limit, offset, err := paginationFromQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deliveries, err := h.repo.GetDeliveries(r.Context(), webhookID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if status != "" {
+		deliveries = slices.DeleteFunc(deliveries, func(d webhook.Delivery) bool {
+			return d.Status != status
+		})
+	}
+	slices.SortFunc(deliveries, func(a, b webhook.Delivery) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	resp := DeliveryListDTO{
+		Deliveries: make([]DeliveryDTO, 0, limit),
+		Total:      len(deliveries),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	for i := offset; i < len(deliveries) && i < offset+limit; i++ {
+		resp.Deliveries = append(resp.Deliveries, NewDeliveryDTO(deliveries[i]))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// webhookForRequest loads the webhook addressed by the {webhook_id} path
+// parameter. On failure it writes the error response itself and returns
+// false.
+func (h *WebhookHandlers) webhookForRequest(w http.ResponseWriter, r *http.Request) (webhook.Webhook, bool) {
+	webhookID, err := idFromURLParam(r, "webhook_id")
+	if err != nil {
+		writeError(w, r, err)
+		return webhook.Webhook{}, false
+	}
+
+	hook, err := h.repo.GetWebhookByID(r.Context(), webhookID)
+	if err != nil {
+		writeError(w, r, err)
+		return webhook.Webhook{}, false
+	}
+
+	return hook, true
+}
+
+func validDeliveryStatus(status string) bool {
+	return status == webhook.StatusPending || status == webhook.StatusDelivered || status == webhook.StatusDead
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"practice-backend/internal/events"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/services/auth"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	wsReadLimit    = 4 << 10
+)
+
+// wsEventTypes are the events sent over the WebSocket.
+var wsEventTypes = []events.Type{
+	events.EntryCreated,
+	events.EntryStatusChanged,
+	events.UserRegistered,
+}
+
+// wsMessage is a message of the server: an event, the confirmation of a
+// subscription or an error.
+type wsMessage struct {
+	Type   string        `json:"type"`
+	ID     uint64        `json:"id,omitempty"`
+	Time   *time.Time    `json:"time,omitempty"`
+	Data   any           `json:"data,omitempty"`
+	Filter *SubscribeDTO `json:"filter,omitempty"`
+	Error  *ErrorDTO     `json:"error,omitempty"`
+}
+
+/*
+pattern: /ws
+method:  GET
+info:    WebSocket upgrade, token in the Authorization header or the
+         access_token query parameter for browsers
+
+succeed:
+  - status code: 101 Switching Protocols
+  - messages: JSON events entry.created, entry.status_changed and
+    user.registered; users get the events of their own entries, admins
+    all. A {"type":"subscribe"} message with types, courses and statuses
+    narrows the events and is answered with "subscribed" or "error"
+failed:
+  - status code: 401, 403(origin), 500
+  - response body: JSON error envelope (code, message, fields, request_id, time)
+*/
+
+// WebSocketHandler pushes events until the client disconnects, can't keep
+// up, is deactivated or deleted, or done is closed. A role change applies
+// to the next events. Origins are checked against the CORS allow-list.
+func WebSocketHandler(bus *events.Bus, authService Auth, cors CORSConfig, done <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUserID(w, r)
+		if !ok {
+			return
+		}
+
+		isAdmin, err := authService.IsAdmin(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, auth.ErrInvalidToken)
+			return
+		}
+
+		if !wsOriginAllowed(r, cors) {
+			writeError(w, r, ErrCORSRejected)
+			return
+		}
+
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			// the origin is checked above, the library would match the
+			// host only and let an http:// page use an https:// origin
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			// Accept has answered already
+			LoggerFromContext(r.Context()).Warn("websocket upgrade failed", "error", err)
+			return
+		}
+		defer conn.CloseNow()
+		conn.SetReadLimit(wsReadLimit)
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		sub, _, _ := bus.Subscribe(0)
+		defer sub.Close()
+
+		// the reader hands filters over to the writer, which owns the
+		// connection for writing
+		filters := make(chan SubscribeDTO)
+		replies := make(chan wsMessage, 1)
+		go func() {
+			defer cancel()
+			readWebSocket(ctx, r, conn, filters, replies)
+		}()
+
+		var filter SubscribeDTO
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+
+		for {
+			var msg wsMessage
+
+			select {
+			case <-ctx.Done():
+				conn.Close(websocket.StatusNormalClosure, "")
+				return
+			case <-done:
+				conn.Close(websocket.StatusGoingAway, "server is shutting down")
+				return
+			case f := <-filters:
+				filter = f
+				continue
+			case msg = <-replies:
+			case e, ok := <-sub.C():
+				if !ok {
+					conn.Close(websocket.StatusTryAgainLater, "client is too slow")
+					return
+				}
+				if userChanged(e, userID) {
+					if isAdmin, err = streamAccess(ctx, authService, userID); err != nil {
+						conn.Close(websocket.StatusPolicyViolation, "access revoked")
+						return
+					}
+				}
+				if !slices.Contains(wsEventTypes, e.Type) || !eventVisible(e, userID, isAdmin) || !filter.matches(e) {
+					continue
+				}
+				msg = wsMessage{Type: string(e.Type), ID: e.ID, Time: &e.Time, Data: EventData(e)}
+			case <-ping.C:
+				pingCtx, cancelPing := context.WithTimeout(ctx, wsWriteTimeout)
+				err := conn.Ping(pingCtx)
+				cancelPing()
+				if err != nil {
+					return
+				}
+				continue
+			}
+
+			if err := writeWebSocket(ctx, conn, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readWebSocket reads subscribe messages until the connection ends.
+func readWebSocket(ctx context.Context, r *http.Request, conn *websocket.Conn, filters chan<- SubscribeDTO, replies chan<- wsMessage) {
+	for {
+		_, b, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		var reply wsMessage
+		var sub SubscribeDTO
+		if err := json.Unmarshal(b, &sub); err != nil {
+			_, errDTO := newErrorDTO(r, invalidJSON(err))
+			reply = wsMessage{Type: "error", Error: &errDTO}
+		} else if err := sub.Validate(); err != nil {
+			_, errDTO := newErrorDTO(r, err)
+			reply = wsMessage{Type: "error", Error: &errDTO}
+		} else {
+			select {
+			case filters <- sub:
+			case <-ctx.Done():
+				return
+			}
+			reply = wsMessage{Type: "subscribed", Filter: &sub}
+		}
+
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func writeWebSocket(ctx context.Context, conn *websocket.Conn, msg wsMessage) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// a client that doesn't read would block the writer, the timeout
+	// drops it
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+
+	return conn.Write(ctx, websocket.MessageText, b)
+}
+
+// matches tells whether the event passes the filter.
+func (s SubscribeDTO) matches(e events.Event) bool {
+	if len(s.Types) > 0 && !slices.Contains(s.Types, string(e.Type)) {
+		return false
+	}
+
+	en, ok := e.Data.(entry.Entry)
+	if !ok {
+		return len(s.Courses) == 0 && len(s.Statuses) == 0
+	}
+	if len(s.Courses) > 0 && !slices.Contains(s.Courses, en.Course) {
+		return false
+	}
+	if len(s.Statuses) > 0 && !slices.Contains(s.Statuses, en.Status) {
+		return false
+	}
+
+	return true
+}
+
+// wsOriginAllowed accepts requests without an Origin, which don't come
+// from browsers, from the host of the server itself, and from origins the
+// CORS allow-list has with the same scheme.
+func wsOriginAllowed(r *http.Request, cors CORSConfig) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return cors.allowsOrigin(origin)
+}
+
+// queryTokenMiddleware lets browsers, which can't set headers on a
+// WebSocket, send the token as the access_token query parameter.
+func queryTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("access_token")
+		if token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
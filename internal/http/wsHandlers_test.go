@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/events"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/user"
+	"practice-backend/internal/services/auth"
+	"practice-backend/internal/storage/inmem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	ctx := t.Context()
+	storage := inmem.NewStorage()
+	bus := events.NewBus(0)
+	authService := auth.NewAuth(storage, nil)
+
+	// the events are relayed by hand, so they come after the subscriptions
+	relay := events.NewRelay(storage, events.RelayConfig{})
+	relay.Handle("bus", events.BusHandler(bus))
+
+	require.NoError(t, authService.CreateAdminUser(ctx, "admin", "admin"))
+	ivanID, err := authService.Register(ctx, user.User{Login: "ivan", Password: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, relay.Flush(ctx))
+
+	server := NewHTTPServer(*NewHTTPHandlers(storage, storage, authService), ServerConfig{Events: bus})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	defer bus.Close()
+
+	dial := func(login string) *websocket.Conn {
+		token, err := authService.Login(ctx, login, map[string]string{"admin": "admin", "ivan": "secret"}[login])
+		require.NoError(t, err)
+
+		conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws?access_token="+token, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.CloseNow() })
+
+		return conn
+	}
+	read := func(conn *websocket.Conn) wsTestMessage {
+		readCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		_, b, err := conn.Read(readCtx)
+		require.NoError(t, err)
+
+		var msg wsTestMessage
+		require.NoError(t, json.Unmarshal(b, &msg))
+		return msg
+	}
+	send := func(conn *websocket.Conn, msg string) {
+		require.NoError(t, conn.Write(ctx, websocket.MessageText, []byte(msg)))
+	}
+
+	admin := dial("admin")
+	ivan := dial("ivan")
+
+	send(admin, `{"type":"subscribe","courses":["Go"]}`)
+	assert.Equal(t, "subscribed", read(admin).Type)
+
+	send(ivan, `{"type":"subscribe","statuses":["done"]}`)
+	msg := read(ivan)
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, CodeValidationFailed, msg.Error.Code)
+	assert.Contains(t, msg.Error.Fields, "statuses")
+
+	_, err = authService.Register(ctx, user.User{Login: "petr", Password: "secret"})
+	require.NoError(t, err)
+	_, err = storage.CreateEntry(ctx, "Rust", time.Now(), ivanID, "card")
+	require.NoError(t, err)
+	e, err := storage.CreateEntry(ctx, "Go", time.Now(), ivanID, "card")
+	require.NoError(t, err)
+	require.NoError(t, relay.Flush(ctx))
+
+	msg = read(admin)
+	assert.Equal(t, string(events.EntryCreated), msg.Type, "user and other course events are filtered out")
+	assert.Equal(t, "Go", msg.Data["course"])
+
+	msg = read(ivan)
+	assert.Equal(t, string(events.EntryCreated), msg.Type, "users don't see registrations")
+	assert.Equal(t, "Rust", msg.Data["course"])
+	assert.Equal(t, "Go", read(ivan).Data["course"])
+
+	_, _, err = storage.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+	require.NoError(t, err)
+	_, err = storage.UpdateUser(ctx, user.User{ID: ivanID, Login: "ivan", Name: "Ivan"})
+	require.NoError(t, err)
+	require.NoError(t, storage.DeleteEntry(ctx, e.ID, entry.AnyVersion))
+	_, err = storage.CreateEntry(ctx, "Go", time.Now(), ivanID, "card")
+	require.NoError(t, err)
+	require.NoError(t, relay.Flush(ctx))
+
+	msg = read(ivan)
+	assert.Equal(t, string(events.EntryStatusChanged), msg.Type)
+	assert.Equal(t, "processed", msg.Data["status"])
+	assert.NotContains(t, msg.Data, "password")
+
+	assert.Equal(t, string(events.EntryStatusChanged), read(admin).Type)
+	assert.Equal(t, string(events.EntryCreated), read(admin).Type, "events not sent over the WebSocket are skipped")
+	assert.Equal(t, string(events.EntryCreated), read(ivan).Type)
+
+	_, _, err = websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws", nil)
+	assert.Error(t, err, "token is required")
+}
+
+type wsTestMessage struct {
+	Type  string         `json:"type"`
+	Data  map[string]any `json:"data"`
+	Error *ErrorDTO      `json:"error"`
+}
+
+func TestWebSocketFollowsAccess(t *testing.T) {
+	ctx := t.Context()
+	storage := inmem.NewStorage()
+	bus := events.NewBus(0)
+	authService := auth.NewAuth(storage, nil)
+
+	relay := events.NewRelay(storage, events.RelayConfig{})
+	relay.Handle("bus", events.BusHandler(bus))
+
+	ivanID, err := authService.Register(ctx, user.User{Login: "ivan", Password: "secret"})
+	require.NoError(t, err)
+	token, err := authService.Login(ctx, "ivan", "secret")
+	require.NoError(t, err)
+	require.NoError(t, relay.Flush(ctx))
+
+	server := NewHTTPServer(*NewHTTPHandlers(storage, storage, authService), ServerConfig{Events: bus})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	defer bus.Close()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws?access_token="+token, nil)
+	require.NoError(t, err)
+	defer conn.CloseNow()
+
+	read := func() (wsTestMessage, error) {
+		readCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		_, b, err := conn.Read(readCtx)
+		if err != nil {
+			return wsTestMessage{}, err
+		}
+		var msg wsTestMessage
+		require.NoError(t, json.Unmarshal(b, &msg))
+		return msg, nil
+	}
+	register := func(login string) {
+		_, err := authService.Register(ctx, user.User{Login: login, Password: "secret"})
+		require.NoError(t, err)
+	}
+
+	_, err = storage.SetAdmin(ctx, ivanID, true)
+	require.NoError(t, err)
+	register("petr")
+	require.NoError(t, relay.Flush(ctx))
+
+	msg, err := read()
+	require.NoError(t, err)
+	assert.Equal(t, string(events.UserRegistered), msg.Type, "a granted admin sees all events")
+
+	_, err = storage.SetAdmin(ctx, ivanID, false)
+	require.NoError(t, err)
+	register("anna")
+	_, err = storage.CreateEntry(ctx, "Go", time.Now(), ivanID, "card")
+	require.NoError(t, err)
+	require.NoError(t, relay.Flush(ctx))
+
+	msg, err = read()
+	require.NoError(t, err)
+	assert.Equal(t, string(events.EntryCreated), msg.Type, "a revoked admin sees own events only")
+
+	_, err = storage.SetDeactivated(ctx, ivanID, true)
+	require.NoError(t, err)
+	require.NoError(t, relay.Flush(ctx))
+
+	_, err = read()
+	assert.Equal(t, websocket.StatusPolicyViolation, websocket.CloseStatus(err), "a deactivated user is disconnected")
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	ctx := t.Context()
+	storage := inmem.NewStorage()
+	authService := auth.NewAuth(storage, nil)
+
+	_, err := authService.Register(ctx, user.User{Login: "ivan", Password: "secret"})
+	require.NoError(t, err)
+	token, err := authService.Login(ctx, "ivan", "secret")
+	require.NoError(t, err)
+
+	server := NewHTTPServer(*NewHTTPHandlers(storage, storage, authService), ServerConfig{
+		CORS: CORSConfig{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"}},
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	testCases := []struct {
+		title  string
+		origin string
+		wantOK bool
+	}{
+		{title: "happy: no origin", wantOK: true},
+		{title: "happy: allowed origin", origin: "https://app.example.com", wantOK: true},
+		{title: "happy: allowed subdomain", origin: "https://app.example.org", wantOK: true},
+		{title: "happy: same host", origin: ts.URL, wantOK: true},
+		{title: "sad: other scheme", origin: "http://app.example.com"},
+		{title: "sad: other origin", origin: "https://evil.example.net"},
+	}
+
+	for _, tc := range testCases {
+		header := http.Header{}
+		if tc.origin != "" {
+			header.Set("Origin", tc.origin)
+		}
+
+		conn, resp, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws?access_token="+token, &websocket.DialOptions{HTTPHeader: header})
+		if !tc.wantOK {
+			assert.Error(t, err, tc.title)
+			if assert.NotNil(t, resp, tc.title) {
+				assert.Equal(t, http.StatusForbidden, resp.StatusCode, tc.title)
+			}
+			continue
+		}
+		if assert.NoError(t, err, tc.title) {
+			conn.CloseNow()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package i18n
+
+// catalog holds the messages by language. Keys are error codes of the API
+// ("USER_EXISTS"), field violations as "<field>.<code>" ("phone.INVALID"),
+// generic violation codes ("REQUIRED") used when a field has no message of
+// its own, and texts of the emails as "mail.<name>" and of the calendars as
+// "calendar.<name>".
+var catalog = map[Language]map[string]string{
+	English: {
+		"INVALID_JSON":            "request body is not valid JSON",
+		"VALIDATION_FAILED":       "request is invalid",
+		"INVALID_ID":              "invalid or empty id",
+		"INVALID_QUERY":           "query parameters are invalid",
+		"INVALID_CREDENTIALS":     "invalid credentials",
+		"INVALID_TOKEN":           "invalid token",
+		"USER_DEACTIVATED":        "user is deactivated",
+		"ACCESS_DENIED":           "access denied",
+		"NOT_ADMIN":               "user is not admin",
+		"OWN_ACCOUNT":             "action is not allowed on own account",
+		"USER_EXISTS":             "user already exists",
+		"USER_NOT_FOUND":          "user not found",
+		"ENTRY_NOT_FOUND":         "entry not found",
+		"WEBHOOK_NOT_FOUND":       "webhook not found",
+		"DELIVERY_NOT_FOUND":      "webhook delivery not found",
+		"CALENDAR_NOT_FOUND":      "calendar not found",
+		"PRECONDITION_FAILED":     "entry was changed by another request, reload it",
+		"PRECONDITION_REQUIRED":   "If-Match header with the entry ETag is required",
+		"ROUTE_NOT_FOUND":         "route not found",
+		"METHOD_NOT_ALLOWED":      "method not allowed",
+		"CORS_REJECTED":           "cross-origin request is not allowed",
+		"RATE_LIMITED":            "too many requests, try again later",
+		"INVALID_IDEMPOTENCY_KEY": "idempotency key must be 1 to 255 printable characters",
+		"IDEMPOTENCY_IN_PROGRESS": "request with this idempotency key is in progress",
+		"IDEMPOTENCY_KEY_REUSED":  "idempotency key was used with another request",
+		"INTERNAL":                "internal error",
+
+		"REQUIRED": "field is required",
+		"INVALID":  "field is invalid",
+
+		"login.REQUIRED":            "login is empty",
+		"password.REQUIRED":         "password is empty",
+		"current_password.REQUIRED": "current password is empty",
+		"new_password.REQUIRED":     "new password is empty",
+		"name.REQUIRED":             "name is empty",
+		"surname.REQUIRED":          "surname is empty",
+		"patronymic.REQUIRED":       "patronymic is empty",
+		"phone.REQUIRED":            "phone number is empty",
+		"phone.INVALID":             "invalid phone number",
+		"email.REQUIRED":            "email is empty",
+		"email.INVALID":             "email is invalid",
+		"language.INVALID":          "language is not supported",
+		"course.REQUIRED":           "course is empty",
+		"date.REQUIRED":             "date is empty",
+		"date.INVALID":              "date is invalid",
+		"payment_method.REQUIRED":   "payment method is empty",
+		"user_id.INVALID":           "user_id invalid or empty",
+		"status.REQUIRED":           "status is empty",
+		"status.INVALID":            "invalid status",
+		"limit.INVALID":             "limit is invalid",
+		"offset.INVALID":            "offset is invalid",
+		"type.INVALID":              "message type must be \"subscribe\"",
+		"types.INVALID":             "event type is unknown",
+		"statuses.INVALID":          "invalid status",
+		"url.REQUIRED":              "url is empty",
+		"url.INVALID":               "url must be an absolute http or https URL",
+		"events.REQUIRED":           "events are empty",
+		"events.INVALID":            "event type is unknown",
+		"secret.INVALID":            "secret must be at least 16 characters",
+		"email_opt_outs.INVALID":    "email kind is unknown",
+
+		"mail.date_layout":              "January 2, 2006",
+		"mail.greeting":                 "Hello",
+		"mail.course":                   "Course",
+		"mail.date":                     "Date",
+		"mail.status_changed.subject":   "Your entry was reviewed",
+		"mail.status_changed.processed": "Your entry was approved, see you at the course.",
+		"mail.status_changed.rejected":  "Unfortunately, your entry was rejected.",
+		"mail.opt_out":                  "You can turn these emails off in your profile.",
+		"mail.time":                     "Time",
+		"mail.reminder.subject":         "Your course starts soon",
+		"mail.reminder.body":            "This is a reminder that your course starts soon.",
+
+		"calendar.name":                 "Courses",
+		"calendar.status.not processed": "Your entry is being reviewed.",
+		"calendar.status.processed":     "Your entry was approved.",
+		"calendar.status.rejected":      "Your entry was rejected, the course is cancelled for you.",
+	},
+	Russian: {
+		"INVALID_JSON":            "тело запроса не является корректным JSON",
+		"VALIDATION_FAILED":       "запрос содержит ошибки",
+		"INVALID_ID":              "некорректный или пустой идентификатор",
+		"INVALID_QUERY":           "некорректные параметры запроса",
+		"INVALID_CREDENTIALS":     "неверный логин или пароль",
+		"INVALID_TOKEN":           "недействительный токен",
+		"USER_DEACTIVATED":        "пользователь деактивирован",
+		"ACCESS_DENIED":           "доступ запрещён",
+		"NOT_ADMIN":               "пользователь не является администратором",
+		"OWN_ACCOUNT":             "действие недоступно для своей учётной записи",
+		"USER_EXISTS":             "пользователь уже существует",
+		"USER_NOT_FOUND":          "пользователь не найден",
+		"ENTRY_NOT_FOUND":         "заявка не найдена",
+		"WEBHOOK_NOT_FOUND":       "вебхук не найден",
+		"DELIVERY_NOT_FOUND":      "доставка вебхука не найдена",
+		"CALENDAR_NOT_FOUND":      "календарь не найден",
+		"PRECONDITION_FAILED":     "заявка изменена другим запросом, загрузите её заново",
+		"PRECONDITION_REQUIRED":   "требуется заголовок If-Match с ETag заявки",
+		"ROUTE_NOT_FOUND":         "маршрут не найден",
+		"METHOD_NOT_ALLOWED":      "метод не поддерживается",
+		"CORS_REJECTED":           "кросс-доменный запрос запрещён",
+		"RATE_LIMITED":            "слишком много запросов, попробуйте позже",
+		"INVALID_IDEMPOTENCY_KEY": "ключ идемпотентности должен содержать от 1 до 255 печатных символов",
+		"IDEMPOTENCY_IN_PROGRESS": "запрос с этим ключом идемпотентности ещё выполняется",
+		"IDEMPOTENCY_KEY_REUSED":  "ключ идемпотентности уже использован для другого запроса",
+		"INTERNAL":                "внутренняя ошибка",
+
+		"REQUIRED": "поле обязательно",
+		"INVALID":  "некорректное значение",
+
+		"login.REQUIRED":            "не указан логин",
+		"password.REQUIRED":         "не указан пароль",
+		"current_password.REQUIRED": "не указан текущий пароль",
+		"new_password.REQUIRED":     "не указан новый пароль",
+		"name.REQUIRED":             "не указано имя",
+		"surname.REQUIRED":          "не указана фамилия",
+		"patronymic.REQUIRED":       "не указано отчество",
+		"phone.REQUIRED":            "не указан номер телефона",
+		"phone.INVALID":             "некорректный номер телефона",
+		"email.REQUIRED":            "не указан email",
+		"email.INVALID":             "некорректный email",
+		"language.INVALID":          "язык не поддерживается",
+		"course.REQUIRED":           "не указан курс",
+		"date.REQUIRED":             "не указана дата",
+		"date.INVALID":              "некорректная дата",
+		"payment_method.REQUIRED":   "не указан способ оплаты",
+		"user_id.INVALID":           "некорректный или пустой user_id",
+		"status.REQUIRED":           "не указан статус",
+		"status.INVALID":            "некорректный статус",
+		"limit.INVALID":             "некорректный limit",
+		"offset.INVALID":            "некорректный offset",
+		"type.INVALID":              "тип сообщения должен быть \"subscribe\"",
+		"types.INVALID":             "неизвестный тип события",
+		"statuses.INVALID":          "некорректный статус",
+		"url.REQUIRED":              "не указан url",
+		"url.INVALID":               "url должен быть абсолютным http или https адресом",
+		"events.REQUIRED":           "не указаны события",
+		"events.INVALID":            "неизвестный тип события",
+		"secret.INVALID":            "секрет должен содержать не менее 16 символов",
+		"email_opt_outs.INVALID":    "неизвестный вид писем",
+
+		"mail.date_layout":              "02.01.2006",
+		"mail.greeting":                 "Здравствуйте",
+		"mail.course":                   "Курс",
+		"mail.date":                     "Дата",
+		"mail.status_changed.subject":   "Ваша заявка рассмотрена",
+		"mail.status_changed.processed": "Ваша заявка одобрена, ждём вас на курсе.",
+		"mail.status_changed.rejected":  "К сожалению, ваша заявка отклонена.",
+		"mail.opt_out":                  "Отключить эти письма можно в профиле.",
+		"mail.time":                     "Время",
+		"mail.reminder.subject":         "Скоро начало курса",
+		"mail.reminder.body":            "Напоминаем, что скоро начнётся ваш курс.",
+
+		"calendar.name":                 "Курсы",
+		"calendar.status.not processed": "Заявка на рассмотрении.",
+		"calendar.status.processed":     "Заявка одобрена.",
+		"calendar.status.rejected":      "Заявка отклонена, курс для вас отменён.",
+	},
+}
@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Language string
+
+const (
+	English Language = "en"
+	Russian Language = "ru"
+
+	Default = English
+)
+
+var ErrUnknownLanguage = errors.New("unknown language")
+
+// Languages are the supported languages.
+var Languages = []Language{English, Russian}
+
+// Parse returns the supported language of a tag such as "ru" or "ru-RU".
+func Parse(tag string) (Language, error) {
+	base, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	lang := Language(strings.ToLower(base))
+
+	if _, ok := catalog[lang]; !ok {
+		return "", ErrUnknownLanguage
+	}
+
+	return lang, nil
+}
+
+// Negotiate picks the best supported language of an Accept-Language
+// header, e.g. "ru-RU,ru;q=0.9,en;q=0.8". It falls back to Default.
+func Negotiate(acceptLanguage string) Language {
+	type candidate struct {
+		lang Language
+		q    float64
+	}
+
+	var candidates []candidate
+	for part := range strings.SplitSeq(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		lang, err := Parse(tag)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{lang: lang, q: q})
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	return candidates[0].lang
+}
+
+// Message returns the text of the key in the language. Missing
+// translations fall back to English.
+func Message(lang Language, key string) (string, bool) {
+	if msg, ok := catalog[lang][key]; ok {
+		return msg, true
+	}
+
+	msg, ok := catalog[Default][key]
+	return msg, ok
+}
@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	testCases := []struct {
+		title          string
+		acceptLanguage string
+		expected       Language
+	}{
+		{
+			title:    "happy: no header",
+			expected: Default,
+		},
+		{
+			title:          "happy: russian with region",
+			acceptLanguage: "ru-RU",
+			expected:       Russian,
+		},
+		{
+			title:          "happy: weights",
+			acceptLanguage: "en;q=0.5, ru;q=0.9",
+			expected:       Russian,
+		},
+		{
+			title:          "happy: first of equal weights",
+			acceptLanguage: "en-US,ru",
+			expected:       English,
+		},
+		{
+			title:          "happy: unsupported languages are skipped",
+			acceptLanguage: "de-DE,fr;q=0.9,ru;q=0.1",
+			expected:       Russian,
+		},
+		{
+			title:          "sad: only unsupported languages",
+			acceptLanguage: "de, *;q=0.5",
+			expected:       Default,
+		},
+		{
+			title:          "sad: zero weight",
+			acceptLanguage: "ru;q=0",
+			expected:       Default,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, Negotiate(tc.acceptLanguage), tc.title)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	msg, ok := Message(Russian, "USER_EXISTS")
+	assert.True(t, ok)
+	assert.Equal(t, "пользователь уже существует", msg)
+
+	_, ok = Message(Russian, "NO_SUCH_KEY")
+	assert.False(t, ok)
+}
+
+func TestCatalogIsComplete(t *testing.T) {
+	for _, lang := range Languages {
+		for key := range catalog[Default] {
+			_, ok := catalog[lang][key]
+			assert.True(t, ok, "%s has no %q", lang, key)
+		}
+	}
+}
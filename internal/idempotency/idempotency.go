@@ -0,0 +1,35 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInProgress = errors.New("request with this idempotency key is in progress")
+	ErrKeyReused  = errors.New("idempotency key was used with another request")
+)
+
+// Response is the first answer to a request, replayed on its retries.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Store keeps the responses by key until their ttl passes. A retry is
+// only replayed when it finds the first response, so with a MemoryStore
+// it has to reach the same process as the first request.
+type Store interface {
+	// Begin reserves key for a request with fingerprint until ttl passes.
+	// It returns the stored response of an earlier request with the same
+	// key, ErrInProgress while that request is served and ErrKeyReused
+	// when it had another fingerprint.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Response, error)
+	// Complete stores the response of the reserved key.
+	Complete(ctx context.Context, key string, resp Response) error
+	// Release frees the reserved key, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
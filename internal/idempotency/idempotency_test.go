@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	resp := Response{StatusCode: http.StatusCreated, Body: []byte(`{"id":1}`)}
+
+	got, err := store.Begin(ctx, "k", "a", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, got, "first request is served")
+
+	_, err = store.Begin(ctx, "k", "a", time.Hour)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	require.NoError(t, store.Complete(ctx, "k", resp))
+
+	got, err = store.Begin(ctx, "k", "a", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, &resp, got, "retry gets the stored response")
+
+	_, err = store.Begin(ctx, "k", "b", time.Hour)
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	now = now.Add(time.Hour)
+	got, err = store.Begin(ctx, "k", "b", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, got, "expired key is reserved again")
+
+	require.NoError(t, store.Release(ctx, "k"))
+	assert.Equal(t, 0, store.Len())
+}
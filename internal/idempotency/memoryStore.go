@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the responses of a single instance until they expire.
+type MemoryStore struct {
+	mtx       *sync.Mutex
+	records   map[string]*record
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type record struct {
+	fingerprint string
+	// response is nil while the request is served
+	response  *Response
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mtx:     new(sync.Mutex),
+		records: make(map[string]*record),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Response, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	rec, ok := s.records[key]
+	if !ok || !now.Before(rec.expiresAt) {
+		s.records[key] = &record{
+			fingerprint: fingerprint,
+			expiresAt:   now.Add(ttl),
+		}
+		return nil, nil
+	}
+
+	if rec.fingerprint != fingerprint {
+		return nil, ErrKeyReused
+	}
+	if rec.response == nil {
+		return nil, ErrInProgress
+	}
+
+	return rec.response, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, resp Response) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if rec, ok := s.records[key]; ok {
+		rec.response = &resp
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
+
+// Len returns the number of kept keys.
+func (s *MemoryStore) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.records)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, rec := range s.records {
+		if !now.Before(rec.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func NewToken(user user.User, duration time.Duration) (string, error) {
+// NewToken issues a token of the user for duration. authTime is when the
+// user logged in with the password, refreshed tokens keep it.
+func NewToken(user user.User, authTime time.Time, duration time.Duration) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
@@ -18,6 +20,7 @@ func NewToken(user user.User, duration time.Duration) (string, error) {
 	claims["phone"] = user.Phone
 	claims["email"] = user.Email
 	claims["name"] = user.Name
+	claims["auth_time"] = authTime.Unix()
 	claims["exp"] = time.Now().Add(duration).Unix()
 
 	tokenString, err := token.SignedString([]byte("TEST_SECRET"))
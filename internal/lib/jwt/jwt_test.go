@@ -28,7 +28,7 @@ func TestNewToken(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			token, err := jwt.NewToken(tc.user, tc.duration)
+			token, err := jwt.NewToken(tc.user, time.Now(), tc.duration)
 			require.ErrorIs(t, err, tc.expectedError)
 
 			require.NotZero(t, token)
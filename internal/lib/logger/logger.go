@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+var (
+	ErrUnknownFormat = errors.New("unknown log format")
+	ErrUnknownLevel  = errors.New("unknown log level")
+)
+
+// piiKeys are attribute keys whose values are never written as is.
+var piiKeys = map[string]struct{}{
+	"password":         {},
+	"current_password": {},
+	"new_password":     {},
+	"phone":            {},
+	"email":            {},
+	"token":            {},
+	"authorization":    {},
+}
+
+type Config struct {
+	// Format is "text" or "json"
+	Format string
+	// Level is "debug", "info", "warn" or "error"
+	Level string
+	// Redact replaces values of PII attributes with Redacted
+	Redact bool
+}
+
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, ErrUnknownLevel
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.Redact {
+		opts.ReplaceAttr = redactPII
+	}
+
+	switch cfg.Format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func redactPII(groups []string, a slog.Attr) slog.Attr {
+	if _, ok := piiKeys[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
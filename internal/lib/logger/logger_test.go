@@ -0,0 +1,67 @@
+package logger_test
+
+import (
+	"bytes"
+	"practice-backend/internal/lib/logger"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           logger.Config
+		expectedError error
+		contains      []string
+		notContains   []string
+	}{
+		{
+			name:        "Redacted JSON",
+			cfg:         logger.Config{Format: "json", Level: "info", Redact: true},
+			contains:    []string{`"email":"[REDACTED]"`, `"user_id":1`},
+			notContains: []string{"ivan@example.com"},
+		},
+		{
+			name:     "Not redacted text",
+			cfg:      logger.Config{Format: "text", Level: "info"},
+			contains: []string{"email=ivan@example.com", "user_id=1"},
+		},
+		{
+			name:        "Level filters messages",
+			cfg:         logger.Config{Format: "text", Level: "error"},
+			notContains: []string{"user_id"},
+		},
+		{
+			name:          "Unknown format",
+			cfg:           logger.Config{Format: "xml", Level: "info"},
+			expectedError: logger.ErrUnknownFormat,
+		},
+		{
+			name:          "Unknown level",
+			cfg:           logger.Config{Format: "json", Level: "loud"},
+			expectedError: logger.ErrUnknownLevel,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			log, err := logger.New(&buf, tc.cfg)
+			require.ErrorIs(t, err, tc.expectedError)
+			if err != nil {
+				return
+			}
+
+			log.Info("user updated", "user_id", 1, "email", "ivan@example.com")
+
+			for _, s := range tc.contains {
+				require.Contains(t, buf.String(), s)
+			}
+			for _, s := range tc.notContains {
+				require.NotContains(t, buf.String(), s)
+			}
+		})
+	}
+}
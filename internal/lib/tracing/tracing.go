@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+var (
+	ErrUnknownExporter = errors.New("unknown trace exporter")
+	ErrFileRequired    = errors.New("trace file is required for the file exporter")
+)
+
+type Config struct {
+	// Exporter is one of none, stdout, file or otlp. The OTLP exporter is
+	// configured with the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter    string
+	File        string
+	ServiceName string
+	// SampleRatio is the share of new traces that are recorded, from 0 to 1.
+	// Traces started by a sampled parent are always recorded.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned func flushes pending spans and must be called
+// on shutdown.
+func Setup(ctx context.Context, cfg Config) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(ctx context.Context) error { return nil }
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+	)
+
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return noop, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if cfg.File == "" {
+			return noop, ErrFileRequired
+		}
+
+		f, openErr := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if openErr != nil {
+			return noop, openErr
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return noop, ErrUnknownExporter
+	}
+	if err != nil {
+		return noop, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return noop, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,63 @@
+package tracing_test
+
+import (
+	"os"
+	"path/filepath"
+	"practice-backend/internal/lib/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           tracing.Config
+		expectedError error
+	}{
+		{
+			name: "None",
+			cfg:  tracing.Config{Exporter: tracing.ExporterNone},
+		},
+		{
+			name:          "Unknown exporter",
+			cfg:           tracing.Config{Exporter: "jaeger"},
+			expectedError: tracing.ErrUnknownExporter,
+		},
+		{
+			name:          "File exporter without file",
+			cfg:           tracing.Config{Exporter: tracing.ExporterFile},
+			expectedError: tracing.ErrFileRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := tracing.Setup(t.Context(), tc.cfg)
+			require.ErrorIs(t, err, tc.expectedError)
+			require.NoError(t, shutdown(t.Context()))
+		})
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := tracing.Setup(t.Context(), tracing.Config{
+		Exporter:    tracing.ExporterFile,
+		File:        path,
+		ServiceName: "test",
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(t.Context(), "some-operation")
+	span.End()
+
+	require.NoError(t, shutdown(t.Context()))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "some-operation")
+}
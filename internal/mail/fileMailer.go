@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes the emails to a directory as .eml files instead of
+// sending them, for local development and tests. Mail clients open the
+// files.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates the directory if it is missing.
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	b, err := msg.Encode(m.from)
+	if err != nil {
+		return err
+	}
+
+	// the names sort in the order the emails were sent
+	name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + rand.Text()[:8] + ".eml"
+
+	return os.WriteFile(filepath.Join(m.dir, name), b, 0o644)
+}
+
+// CheckHealth makes sure emails can be written to the directory.
+func (m *FileMailer) CheckHealth(ctx context.Context) error {
+	f, err := os.CreateTemp(m.dir, ".health-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,109 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAddress = errors.New("invalid email address")
+	// ErrRejected is returned when the server refused the recipient or
+	// the email for good, sending it again gets the same answer
+	ErrRejected = errors.New("email rejected")
+)
+
+// Message is an email with a plain text and an HTML version of the body,
+// mail clients show the one they support.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends emails. Send may be called concurrently. An email that
+// can never be sent fails with ErrInvalidAddress or ErrRejected, other
+// errors may go away when it is sent again.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Encode returns the message from the sender as a MIME email with
+// CRLF line endings, ready for SMTP.
+func (m Message) Encode(from string) ([]byte, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from: %w", ErrInvalidAddress, err)
+	}
+	toAddr, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to: %w", ErrInvalidAddress, err)
+	}
+
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := []struct{ key, value string }{
+		{"From", fromAddr.String()},
+		{"To", toAddr.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", rand.Text(), domain(fromAddr.Address))},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", body.Boundary())},
+	}
+	var head bytes.Buffer
+	for _, h := range header {
+		fmt.Fprintf(&head, "%s: %s\r\n", h.key, h.value)
+	}
+	head.WriteString("\r\n")
+
+	// the last part is the preferred one
+	if err := writePart(body, "text/plain", m.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(body, "text/html", m.HTML); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+func writePart(w *multipart.Writer, contentType string, content string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := io.WriteString(qp, content); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+func domain(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+
+	return "localhost"
+}
@@ -0,0 +1,155 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	testCases := []struct {
+		title   string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{title: "happy: plain addresses", from: "noreply@example.com", to: "ivan@example.com"},
+		{title: "happy: addresses with names", from: "Practice <noreply@example.com>", to: "Иван <ivan@example.com>"},
+		{title: "sad: invalid recipient", from: "noreply@example.com", to: "ivan", wantErr: ErrInvalidAddress},
+		{title: "sad: header injection", from: "noreply@example.com", to: "ivan@example.com\r\nBcc: all@example.com", wantErr: ErrInvalidAddress},
+		{title: "sad: invalid sender", from: "", to: "ivan@example.com", wantErr: ErrInvalidAddress},
+	}
+
+	for _, tc := range testCases {
+		msg := Message{To: tc.to, Subject: "Заявка: Go", Text: "Здравствуйте!", HTML: "<p>Здравствуйте!</p>"}
+
+		b, err := msg.Encode(tc.from)
+		if tc.wantErr != nil {
+			assert.ErrorIs(t, err, tc.wantErr, tc.title)
+			continue
+		}
+		require.NoError(t, err, tc.title)
+
+		parsed, err := mail.ReadMessage(bytes.NewReader(b))
+		require.NoError(t, err, tc.title)
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		require.NoError(t, err, tc.title)
+		assert.Equal(t, "Заявка: Go", subject, tc.title)
+		assert.Empty(t, parsed.Header.Get("Bcc"), tc.title)
+
+		mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+		require.NoError(t, err, tc.title)
+		assert.Equal(t, "multipart/alternative", mediaType, tc.title)
+
+		parts := multipart.NewReader(parsed.Body, params["boundary"])
+		var bodies []string
+		for {
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, tc.title)
+
+			body, err := io.ReadAll(part)
+			require.NoError(t, err, tc.title)
+			bodies = append(bodies, part.Header.Get("Content-Type")+": "+string(body))
+		}
+		assert.Equal(t, []string{
+			"text/plain; charset=utf-8: Здравствуйте!",
+			"text/html; charset=utf-8: <p>Здравствуйте!</p>",
+		}, bodies, tc.title)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "noreply@example.com")
+	require.NoError(t, err)
+	require.NoError(t, m.CheckHealth(t.Context()))
+
+	require.NoError(t, m.Send(t.Context(), Message{To: "ivan@example.com", Subject: "Hi", Text: "Hi"}))
+	assert.Error(t, m.Send(t.Context(), Message{To: "ivan"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "only the sent email is written")
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+
+	b, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "To: <ivan@example.com>\r\n")
+}
+
+// serveSMTP answers one SMTP session on l, replying rcptReply to RCPT.
+func serveSMTP(l net.Listener, rcptReply string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	c := textproto.NewConn(conn)
+	defer c.Close()
+
+	c.PrintfLine("220 localhost")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(strings.Fields(line)[0]) {
+		case "RCPT":
+			c.PrintfLine("%s", rcptReply)
+		case "DATA":
+			c.PrintfLine("354 go on")
+			if _, err := c.ReadDotBytes(); err != nil {
+				return
+			}
+			c.PrintfLine("250 queued")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("250 localhost")
+		}
+	}
+}
+
+func TestSMTPMailerRejected(t *testing.T) {
+	testCases := []struct {
+		title        string
+		rcptReply    string
+		wantErr      bool
+		wantRejected bool
+	}{
+		{title: "happy: sent", rcptReply: "250 ok"},
+		{title: "sad: unknown recipient", rcptReply: "550 no such user", wantErr: true, wantRejected: true},
+		{title: "sad: mailbox busy", rcptReply: "450 try later", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err, tc.title)
+		go serveSMTP(l, tc.rcptReply)
+
+		m, err := NewSMTPMailer(SMTPConfig{Addr: l.Addr().String(), From: "noreply@example.com"})
+		require.NoError(t, err, tc.title)
+
+		err = m.Send(t.Context(), Message{To: "ivan@example.com", Subject: "Hi", Text: "Hi"})
+		l.Close()
+
+		assert.Equal(t, tc.wantErr, err != nil, tc.title)
+		assert.Equal(t, tc.wantRejected, errors.Is(err, ErrRejected), tc.title)
+	}
+}
@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+const DefaultSMTPTimeout = 10 * time.Second
+
+type SMTPConfig struct {
+	// Addr is the host:port of the SMTP server
+	Addr string
+	// Username and Password authenticate with PLAIN when Username is set.
+	// The server must offer STARTTLS unless it is on localhost.
+	Username string
+	Password string
+	// From is the sender, e.g. "Practice <noreply@example.com>"
+	From string
+	// Timeout of sending an email, connecting included
+	Timeout time.Duration
+}
+
+// SMTPMailer sends emails through an SMTP server, with STARTTLS when the
+// server offers it. Every email is sent over a new connection.
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	host string
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, ErrInvalidAddress
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSMTPTimeout
+	}
+
+	return &SMTPMailer{
+		cfg:  cfg,
+		host: host,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	b, err := msg.Encode(m.cfg.From)
+	if err != nil {
+		return err
+	}
+	from, _ := mail.ParseAddress(m.cfg.From)
+	to, _ := mail.ParseAddress(msg.To)
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return rejected(err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return rejected(err)
+	}
+
+	return c.Quit()
+}
+
+// rejected wraps a permanent negative reply of the server to the
+// recipient or the email into ErrRejected.
+func rejected(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+
+	return err
+}
+
+// CheckHealth makes sure the SMTP server accepts connections.
+func (m *SMTPMailer) CheckHealth(ctx context.Context) error {
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Noop(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// dial connects to the server, the connection ends with ctx.
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
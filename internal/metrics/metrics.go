@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "practice_backend"
+
+const (
+	LoginSuccess     = "success"
+	LoginFailure     = "failure"
+	LoginDeactivated = "deactivated"
+)
+
+const (
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+	WebhookDead      = "dead"
+)
+
+// Metrics holds the application collectors. All methods are safe to call
+// on a nil *Metrics, which records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	logins            *prometheus.CounterVec
+	registrations     prometheus.Counter
+	entriesCreated    *prometheus.CounterVec
+	entryTransitions  *prometheus.CounterVec
+	storageOperations *prometheus.HistogramVec
+	rateLimited       *prometheus.CounterVec
+	webhookAttempts   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, chi route pattern and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, chi route pattern and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result.",
+		}, []string{"result"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Registered users.",
+		}),
+		entriesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "entries_created_total",
+			Help:      "Created entries by initial status.",
+		}, []string{"status"}),
+		entryTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "entry_status_transitions_total",
+			Help:      "Entry status changes by previous and new status.",
+		}, []string{"from", "to"}),
+		storageOperations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Repository call latency by repository, operation and result.",
+			Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}, []string{"repo", "operation", "result"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "Requests rejected by the rate limiter by policy.",
+		}, []string{"policy"}),
+		webhookAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_delivery_attempts_total",
+			Help:      "Webhook delivery attempts by result: delivered, failed and to be retried, or dead.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.logins,
+		m.registrations,
+		m.entriesCreated,
+		m.entryTransitions,
+		m.storageOperations,
+		m.rateLimited,
+		m.webhookAttempts,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry is exposed for tests and for registering extra collectors.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Metrics) ObserveHTTPRequest(method string, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	statusStr := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, statusStr).Inc()
+	m.httpDuration.WithLabelValues(method, route, statusStr).Observe(duration.Seconds())
+}
+
+func (m *Metrics) IncLogin(result string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) IncRegistration() {
+	if m == nil {
+		return
+	}
+	m.registrations.Inc()
+}
+
+func (m *Metrics) IncEntryCreated(status string) {
+	if m == nil {
+		return
+	}
+	m.entriesCreated.WithLabelValues(status).Inc()
+}
+
+func (m *Metrics) IncEntryTransition(from string, to string) {
+	if m == nil {
+		return
+	}
+	m.entryTransitions.WithLabelValues(from, to).Inc()
+}
+
+func (m *Metrics) ObserveStorageOperation(repo string, operation string, err error, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.storageOperations.WithLabelValues(repo, operation, result).Observe(duration.Seconds())
+}
+
+func (m *Metrics) IncRateLimited(policy string) {
+	if m == nil {
+		return
+	}
+	m.rateLimited.WithLabelValues(policy).Inc()
+}
+
+func (m *Metrics) IncWebhookAttempt(result string) {
+	if m == nil {
+		return
+	}
+	m.webhookAttempts.WithLabelValues(result).Inc()
+}
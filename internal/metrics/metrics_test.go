@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRecord(t *testing.T) {
+	testCases := []struct {
+		title  string
+		record func(m *Metrics)
+		name   string
+		want   string
+	}{
+		{
+			title:  "happy: login",
+			record: func(m *Metrics) { m.IncLogin(LoginFailure) },
+			name:   "practice_backend_logins_total",
+			want: `
+# HELP practice_backend_logins_total Login attempts by result.
+# TYPE practice_backend_logins_total counter
+practice_backend_logins_total{result="failure"} 1
+`,
+		},
+		{
+			title:  "happy: registration",
+			record: func(m *Metrics) { m.IncRegistration(); m.IncRegistration() },
+			name:   "practice_backend_registrations_total",
+			want: `
+# HELP practice_backend_registrations_total Registered users.
+# TYPE practice_backend_registrations_total counter
+practice_backend_registrations_total 2
+`,
+		},
+		{
+			title:  "happy: rate limited",
+			record: func(m *Metrics) { m.IncRateLimited("auth") },
+			name:   "practice_backend_rate_limited_requests_total",
+			want: `
+# HELP practice_backend_rate_limited_requests_total Requests rejected by the rate limiter by policy.
+# TYPE practice_backend_rate_limited_requests_total counter
+practice_backend_rate_limited_requests_total{policy="auth"} 1
+`,
+		},
+		{
+			title:  "happy: webhook attempt",
+			record: func(m *Metrics) { m.IncWebhookAttempt(WebhookDead) },
+			name:   "practice_backend_webhook_delivery_attempts_total",
+			want: `
+# HELP practice_backend_webhook_delivery_attempts_total Webhook delivery attempts by result: delivered, failed and to be retried, or dead.
+# TYPE practice_backend_webhook_delivery_attempts_total counter
+practice_backend_webhook_delivery_attempts_total{result="dead"} 1
+`,
+		},
+		{
+			title: "happy: HTTP request",
+			record: func(m *Metrics) {
+				m.ObserveHTTPRequest(http.MethodGet, "/api/entry/{entry_id}", http.StatusNotFound, time.Millisecond)
+			},
+			name: "practice_backend_http_requests_total",
+			want: `
+# HELP practice_backend_http_requests_total HTTP requests by method, chi route pattern and status.
+# TYPE practice_backend_http_requests_total counter
+practice_backend_http_requests_total{method="GET",route="/api/entry/{entry_id}",status="404"} 1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := New()
+		tc.record(m)
+
+		err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(tc.want), tc.name)
+		assert.NoError(t, err, tc.title)
+	}
+}
+
+func TestMetricsStorageOperationResult(t *testing.T) {
+	m := New()
+	m.ObserveStorageOperation("entry", "get_entry_by_id", nil, time.Millisecond)
+	m.ObserveStorageOperation("entry", "get_entry_by_id", errors.New("not found"), time.Millisecond)
+
+	count, err := testutil.GatherAndCount(m.Registry(), "practice_backend_storage_operation_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "ok and error are apart")
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+
+	assert.NotPanics(t, func() {
+		m.ObserveHTTPRequest(http.MethodGet, "/", http.StatusOK, time.Millisecond)
+		m.IncLogin(LoginSuccess)
+		m.IncRegistration()
+		m.IncEntryCreated("not processed")
+		m.IncEntryTransition("not processed", "processed")
+		m.ObserveStorageOperation("entry", "get_entries", nil, time.Millisecond)
+		m.IncRateLimited("api")
+		m.IncWebhookAttempt(WebhookDelivered)
+	})
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := New()
+	m.IncEntryCreated("not processed")
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `practice_backend_entries_created_total{status="not processed"} 1`)
+	assert.Contains(t, string(body), "go_goroutines", "runtime metrics are collected")
+}
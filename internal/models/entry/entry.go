@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// AnyVersion skips the version check of EntryRepo updates and deletes.
+const AnyVersion = 0
+
 type Entry struct {
 	ID            int
 	Course        string
@@ -12,6 +15,8 @@ type Entry struct {
 	UserID        int
 	PaymentMethod string
 	Status        string
+	// Version starts at 1 and grows with every change of the entry
+	Version int
 }
 
 func NewEntry(course string, date time.Time, userID int, paymentMethod string) *Entry {
@@ -21,11 +26,21 @@ func NewEntry(course string, date time.Time, userID int, paymentMethod string) *
 		UserID:        userID,
 		PaymentMethod: paymentMethod,
 		Status:        "not processed",
+		Version:       1,
 	}
 }
 
+// StartsAt is when the course of the entry starts: its date at timeOfDay
+// in loc, e.g. 9:00 in Europe/Moscow.
+func (e Entry) StartsAt(loc *time.Location, timeOfDay time.Duration) time.Time {
+	y, m, d := e.Date.Date()
+
+	return time.Date(y, m, d, 0, 0, 0, 0, loc).Add(timeOfDay)
+}
+
 func (e *Entry) UpdateStatus(status string) *Entry {
 	e.Status = status
+	e.Version++
 	return e
 }
 
@@ -39,6 +54,11 @@ type EntryRepo interface {
 	) (Entry, error)
 	GetEntryByID(ctx context.Context, id int) (Entry, error)
 	GetEntries(ctx context.Context) ([]Entry, error)
-	DeleteEntry(ctx context.Context, id int) error
-	UpdateStatusEntry(ctx context.Context, id int, status string) (Entry, error)
+	// DeleteEntry and UpdateStatusEntry fail when the entry is not at
+	// version, unless it is AnyVersion. The check and the change are atomic.
+	DeleteEntry(ctx context.Context, id int, version int) error
+	// UpdateStatusEntry also returns the status the entry had right
+	// before the change. Setting the status the entry already has
+	// changes nothing, the entry keeps its version.
+	UpdateStatusEntry(ctx context.Context, id int, status string, version int) (e Entry, previous string, err error)
 }
@@ -0,0 +1,17 @@
+package lease
+
+import (
+	"context"
+	"time"
+)
+
+// LeaseRepo hands out named leases, so one replica at a time does a job,
+// e.g. sending reminders.
+type LeaseRepo interface {
+	// AcquireLease gives the lease to holder for ttl, or extends it when
+	// holder has it already. It reports false when another holder has an
+	// unexpired lease.
+	AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives the lease up, if holder has it.
+	ReleaseLease(ctx context.Context, name string, holder string) error
+}
@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"context"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/reminder"
+	"practice-backend/internal/models/user"
+	"time"
+)
+
+// Types of the domain events.
+const (
+	EntryCreated       = "entry.created"
+	EntryStatusChanged = "entry.status_changed"
+	EntryDeleted       = "entry.deleted"
+	// EntryReminder is due when the course of an approved entry starts
+	// soon
+	EntryReminder  = "entry.reminder"
+	UserRegistered = "user.registered"
+	UserUpdated    = "user.updated"
+	UserDeleted    = "user.deleted"
+)
+
+// Message is a domain event recorded by the repo in the same mutation as
+// the change it describes, so a change is never saved without its event
+// or the other way round.
+type Message struct {
+	// ID grows with every message, messages are relayed in its order
+	ID   int
+	Type string
+	Time time.Time
+	// UserID is the user the event concerns, e.g. the entry owner
+	UserID int
+	// Entry is set for entry events, the entry after the change or the
+	// deleted one
+	Entry *entry.Entry
+	// User is set for user events, without the password
+	User *user.User
+	// Reminder is set for entry.reminder
+	Reminder *reminder.Reminder
+}
+
+type OutboxRepo interface {
+	// PendingMessages returns up to limit messages after the message with
+	// afterID not deleted yet, the oldest first.
+	PendingMessages(ctx context.Context, afterID int, limit int) ([]Message, error)
+	// DeleteMessages deletes the relayed messages up to the one with
+	// throughID.
+	DeleteMessages(ctx context.Context, throughID int) error
+	// Handled reports whether the handler with the name marked the
+	// message with messageID as handled.
+	Handled(ctx context.Context, name string, messageID int) (bool, error)
+	// MarkHandled records that the handler with the name handled the
+	// message with messageID, so the handler can skip it when the message
+	// is relayed again after a restart. Marks are deleted with their
+	// message.
+	MarkHandled(ctx context.Context, name string, messageID int) error
+}
@@ -0,0 +1,27 @@
+package reminder
+
+import (
+	"context"
+	"practice-backend/internal/models/entry"
+	"time"
+)
+
+// Reminder tells the owner of an approved entry that the course starts
+// soon.
+type Reminder struct {
+	Entry entry.Entry
+	// Before is how long before the course start the reminder is due,
+	// one of the configured offsets
+	Before   time.Duration
+	StartsAt time.Time
+}
+
+type ReminderRepo interface {
+	// RecordReminder records the reminder as sent and adds its event to
+	// the outbox, unless the reminder of the entry with the same Before
+	// is recorded already. It reports whether it recorded the reminder.
+	RecordReminder(ctx context.Context, r Reminder) (bool, error)
+	// PruneReminders deletes the records of reminders of courses started
+	// before and returns how many were deleted.
+	PruneReminders(ctx context.Context, before time.Time) (int, error)
+}
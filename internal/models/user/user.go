@@ -1,6 +1,9 @@
 package user
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 type User struct {
 	ID    int
@@ -10,9 +13,37 @@ type User struct {
 	Name       string
 	Surname    string
 	Patronymic string
-	Phone      string
-	Email      string
-	IsAdmin    bool
+	// Phone is in E.164, e.g. +79991234567
+	Phone   string
+	Email   string
+	IsAdmin bool
+	// Deactivated users can't log in
+	Deactivated bool
+	// Language of messages, "en" or "ru". Empty means the language of
+	// the client is used.
+	Language string
+	// EmailOptOuts are the kinds of emails the user doesn't want, all
+	// kinds are sent by default
+	EmailOptOuts []string
+	// CalendarToken is the secret in the URL of the user's calendar
+	// feed, empty when the feed is off
+	CalendarToken string
+}
+
+// Kinds of emails a user can opt out of.
+const (
+	// EmailStatusChanges tell about the review of the user's entries
+	EmailStatusChanges = "status_changes"
+	// EmailReminders remind of the start of the user's courses
+	EmailReminders = "reminders"
+)
+
+// EmailKinds are the kinds of emails a user can opt out of.
+var EmailKinds = []string{EmailStatusChanges, EmailReminders}
+
+// WantsEmail reports whether emails of the kind are sent to the user.
+func (u User) WantsEmail(kind string) bool {
+	return !slices.Contains(u.EmailOptOuts, kind)
 }
 
 func NewUser(
@@ -52,4 +83,18 @@ type UserRepo interface {
 	) (User, error)
 	GetUserByID(ctx context.Context, id int) (User, error)
 	GetUserByLogin(ctx context.Context, login string) (User, error)
+	GetUserByCalendarToken(ctx context.Context, token string) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	// UpdateUser replaces the profile of the stored user with the same ID.
+	// The password, the admin role, the deactivation and the calendar
+	// token are kept as is, they are changed by their own methods, so a
+	// profile update made meanwhile doesn't undo them.
+	UpdateUser(ctx context.Context, user User) (User, error)
+	SetAdmin(ctx context.Context, id int, isAdmin bool) (User, error)
+	SetDeactivated(ctx context.Context, id int, deactivated bool) (User, error)
+	// SetCalendarToken replaces the calendar token, empty turns the feed
+	// off.
+	SetCalendarToken(ctx context.Context, id int, token string) (User, error)
+	ChangePassword(ctx context.Context, id int, password string) error
+	DeleteUser(ctx context.Context, id int) error
 }
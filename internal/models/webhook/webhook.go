@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Statuses of a delivery.
+const (
+	// StatusPending deliveries are sent when NextAttemptAt comes
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusDead deliveries ran out of attempts, they stay in the
+	// dead-letter list until retried by an admin
+	StatusDead = "dead"
+)
+
+// Webhook is a subscription of an external system to events.
+type Webhook struct {
+	ID  int
+	URL string
+	// Events are the event types sent to the URL, e.g. "entry.created"
+	Events []string
+	// Secret signs the payloads, receivers check the signature with it
+	Secret    string
+	CreatedAt time.Time
+}
+
+func NewWebhook(url string, events []string, secret string) *Webhook {
+	return &Webhook{
+		URL:       url,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Wants reports whether events of eventType are sent to the webhook.
+func (w Webhook) Wants(eventType string) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// Delivery is an event sent, or still to be sent, to a webhook.
+type Delivery struct {
+	ID        int
+	WebhookID int
+	EventType string
+	// Payload is the request body, the same for every attempt
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastAttemptAt time.Time
+	// ResponseStatus is the status code of the last attempt, 0 when
+	// there was no response
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+func NewDelivery(webhookID int, eventType string, payload json.RawMessage) *Delivery {
+	now := time.Now().UTC()
+
+	return &Delivery{
+		WebhookID:     webhookID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+type WebhookRepo interface {
+	CreateWebhook(ctx context.Context, url string, events []string, secret string) (Webhook, error)
+	GetWebhookByID(ctx context.Context, id int) (Webhook, error)
+	GetWebhooks(ctx context.Context) ([]Webhook, error)
+	// DeleteWebhook deletes the deliveries of the webhook too.
+	DeleteWebhook(ctx context.Context, id int) error
+
+	CreateDelivery(ctx context.Context, d Delivery) (Delivery, error)
+	GetDeliveryByID(ctx context.Context, id int) (Delivery, error)
+	// GetDeliveries returns the deliveries of the webhook, or of all
+	// webhooks when webhookID is negative, oldest first.
+	GetDeliveries(ctx context.Context, webhookID int) ([]Delivery, error)
+	// DueDeliveries returns up to limit pending deliveries whose next
+	// attempt is not after now, the longest waiting first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+	UpdateDelivery(ctx context.Context, d Delivery) (Delivery, error)
+	// PruneDeliveries deletes the delivered deliveries created before
+	// and returns how many were deleted. Dead ones are kept.
+	PruneDeliveries(ctx context.Context, before time.Time) (int, error)
+}
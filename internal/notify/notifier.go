@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"practice-backend/internal/events"
+	"practice-backend/internal/i18n"
+	"practice-backend/internal/mail"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/models/reminder"
+	"practice-backend/internal/models/user"
+	"practice-backend/internal/storage/inmem"
+	"slices"
+)
+
+// HandlerName is the name the notifier is registered with at the relay,
+// its marks of the sent emails are kept under it.
+const HandlerName = "mail"
+
+// notifiedStatuses are the statuses of a reviewed entry, its owner is
+// emailed when the entry gets one of them.
+var notifiedStatuses = []string{"processed", "rejected"}
+
+// Notifier emails students about their entries: when they are reviewed
+// and before their courses start.
+type Notifier struct {
+	users   user.UserRepo
+	entries entry.EntryRepo
+	// outbox keeps which events were emailed about, so they aren't
+	// emailed again when the relay hands them over after a restart
+	outbox outbox.OutboxRepo
+	mailer mail.Mailer
+	logger *slog.Logger
+}
+
+func NewNotifier(users user.UserRepo, entries entry.EntryRepo, outbox outbox.OutboxRepo, mailer mail.Mailer, logger *slog.Logger) *Notifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Notifier{
+		users:   users,
+		entries: entries,
+		outbox:  outbox,
+		mailer:  mailer,
+		logger:  logger,
+	}
+}
+
+// Handle is an events.Handler. It emails the owner of an entry that was
+// reviewed, and the owner of an entry still approved whose reminder is due,
+// unless the owner opted out, is deactivated or has no email. An email
+// that failed is sent again by the relay, unless it can never be sent:
+// then it is logged and dropped, so it doesn't hold back the others. An
+// event from the outbox is emailed about once, even when it is handed
+// over again.
+func (n *Notifier) Handle(ctx context.Context, e events.Event) error {
+	if e.MessageID != 0 {
+		sent, err := n.outbox.Handled(ctx, HandlerName, e.MessageID)
+		if err != nil || sent {
+			return err
+		}
+	}
+
+	switch data := e.Data.(type) {
+	case entry.Entry:
+		if e.Type != events.EntryStatusChanged || !slices.Contains(notifiedStatuses, data.Status) {
+			return nil
+		}
+		return n.send(ctx, e.MessageID, data, user.EmailStatusChanges, func(u user.User) (mail.Message, error) {
+			return StatusChangedMessage(u, data)
+		})
+	case reminder.Reminder:
+		// the entry may have been rejected, moved or deleted since the
+		// reminder was recorded
+		current, err := n.entries.GetEntryByID(ctx, data.Entry.ID)
+		if err != nil {
+			if errors.Is(err, inmem.ErrEntryNotFound) {
+				return nil
+			}
+			return err
+		}
+		if current.Status != "processed" || !current.Date.Equal(data.Entry.Date) {
+			return nil
+		}
+		return n.send(ctx, e.MessageID, data.Entry, user.EmailReminders, func(u user.User) (mail.Message, error) {
+			return ReminderMessage(u, data)
+		})
+	}
+
+	return nil
+}
+
+// send emails the message about the entry to its owner, if the owner
+// wants emails of the kind, and marks the outbox message with messageID
+// as sent.
+func (n *Notifier) send(ctx context.Context, messageID int, en entry.Entry, kind string, message func(u user.User) (mail.Message, error)) error {
+	u, err := n.users.GetUserByID(ctx, en.UserID)
+	if err != nil {
+		if errors.Is(err, inmem.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if u.Deactivated || u.Email == "" || !u.WantsEmail(kind) {
+		return nil
+	}
+
+	msg, err := message(u)
+	if err != nil {
+		return err
+	}
+	if err := n.mailer.Send(ctx, msg); err != nil {
+		if errors.Is(err, mail.ErrInvalidAddress) || errors.Is(err, mail.ErrRejected) {
+			n.logger.Warn("email dropped", "kind", kind, "user_id", u.ID, "entry_id", en.ID, "error", err)
+			return nil
+		}
+		return err
+	}
+
+	n.logger.Info("email sent", "kind", kind, "user_id", u.ID, "entry_id", en.ID)
+
+	if messageID != 0 {
+		// the email is out, failing the event would only send it again
+		if err := n.outbox.MarkHandled(ctx, HandlerName, messageID); err != nil {
+			n.logger.Error("email not marked as sent", "message_id", messageID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// StatusChangedMessage is the email telling the user about the review of
+// the entry, in the language of the user.
+func StatusChangedMessage(u user.User, en entry.Entry) (mail.Message, error) {
+	lang := language(u)
+	layout, _ := i18n.Message(lang, "mail.date_layout")
+	subject, _ := i18n.Message(lang, "mail.status_changed.subject")
+
+	text, html, err := render("status_changed", lang, struct {
+		Lang   i18n.Language
+		Name   string
+		Course string
+		Date   string
+		Status string
+	}{
+		Lang:   lang,
+		Name:   u.Name,
+		Course: en.Course,
+		Date:   en.Date.Format(layout),
+		Status: en.Status,
+	})
+	if err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{
+		To:      u.Email,
+		Subject: subject + ": " + en.Course,
+		Text:    text,
+		HTML:    html,
+	}, nil
+}
+
+// ReminderMessage is the email reminding the user of the course start,
+// in the language of the user.
+func ReminderMessage(u user.User, r reminder.Reminder) (mail.Message, error) {
+	lang := language(u)
+	layout, _ := i18n.Message(lang, "mail.date_layout")
+	subject, _ := i18n.Message(lang, "mail.reminder.subject")
+
+	text, html, err := render("reminder", lang, struct {
+		Lang   i18n.Language
+		Name   string
+		Course string
+		Date   string
+		Time   string
+	}{
+		Lang:   lang,
+		Name:   u.Name,
+		Course: r.Entry.Course,
+		Date:   r.StartsAt.Format(layout),
+		Time:   r.StartsAt.Format("15:04"),
+	})
+	if err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{
+		To:      u.Email,
+		Subject: subject + ": " + r.Entry.Course,
+		Text:    text,
+		HTML:    html,
+	}, nil
+}
+
+func language(u user.User) i18n.Language {
+	lang, err := i18n.Parse(u.Language)
+	if err != nil {
+		return i18n.Default
+	}
+
+	return lang
+}
@@ -0,0 +1,286 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"practice-backend/internal/events"
+	"practice-backend/internal/mail"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/reminder"
+	"practice-backend/internal/models/user"
+	"practice-backend/internal/storage/inmem"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a mailer keeping the sent emails.
+type recorder struct {
+	mtx  sync.Mutex
+	sent []mail.Message
+	err  error
+}
+
+func (r *recorder) Send(ctx context.Context, m mail.Message) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, m)
+	return nil
+}
+
+func TestNotifierHandle(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	newUser := func(login string, change func(u *user.User)) int {
+		u, err := storage.CreateUser(ctx, login, "hash", "Ivan", "", "", "", login+"@example.com", false)
+		require.NoError(t, err)
+		change(&u)
+		_, err = storage.UpdateUser(ctx, u)
+		require.NoError(t, err)
+		return u.ID
+	}
+	english := newUser("english", func(u *user.User) {})
+	russian := newUser("russian", func(u *user.User) { u.Language = "ru" })
+	optedOut := newUser("opted_out", func(u *user.User) { u.EmailOptOuts = []string{user.EmailStatusChanges} })
+	deactivated := newUser("deactivated", func(u *user.User) {})
+	_, err := storage.SetDeactivated(ctx, deactivated, true)
+	require.NoError(t, err)
+	noEmail := newUser("no_email", func(u *user.User) { u.Email = "" })
+	noReminders := newUser("no_reminders", func(u *user.User) { u.EmailOptOuts = []string{user.EmailReminders} })
+
+	date := time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC)
+	statusChanged := func(userID int, status string) events.Event {
+		return events.Event{
+			Type: events.EntryStatusChanged,
+			Data: entry.Entry{Course: "Go", Date: date, UserID: userID, Status: status},
+		}
+	}
+	newEntry := func(userID int, status string) entry.Entry {
+		e, err := storage.CreateEntry(ctx, "Go", date, userID, "card")
+		require.NoError(t, err)
+		if status != e.Status {
+			e, _, err = storage.UpdateStatusEntry(ctx, e.ID, status, e.Version)
+			require.NoError(t, err)
+		}
+		return e
+	}
+	remindOf := func(e entry.Entry) events.Event {
+		return events.Event{
+			Type: events.EntryReminder,
+			Data: reminder.Reminder{
+				Entry:    e,
+				Before:   2 * time.Hour,
+				StartsAt: e.Date.Add(9 * time.Hour),
+			},
+		}
+	}
+	remind := func(userID int) events.Event {
+		return remindOf(newEntry(userID, "processed"))
+	}
+	rejected := newEntry(english, "processed")
+	reminderOfRejected := remindOf(rejected)
+	_, _, err = storage.UpdateStatusEntry(ctx, rejected.ID, "rejected", rejected.Version)
+	require.NoError(t, err)
+	deleted := newEntry(english, "processed")
+	reminderOfDeleted := remindOf(deleted)
+	require.NoError(t, storage.DeleteEntry(ctx, deleted.ID, entry.AnyVersion))
+	moved := newEntry(english, "processed")
+	moved.Date = date.AddDate(0, 0, -1)
+	reminderOfMoved := remindOf(moved)
+
+	testCases := []struct {
+		title       string
+		event       events.Event
+		wantTo      string
+		wantSubject string
+		wantText    []string
+	}{
+		{
+			title:       "happy: processed",
+			event:       statusChanged(english, "processed"),
+			wantTo:      "english@example.com",
+			wantSubject: "Your entry was reviewed: Go",
+			wantText:    []string{"Hello, Ivan!", "approved", "October 5, 2025"},
+		},
+		{
+			title:       "happy: rejected in the language of the user",
+			event:       statusChanged(russian, "rejected"),
+			wantTo:      "russian@example.com",
+			wantSubject: "Ваша заявка рассмотрена: Go",
+			wantText:    []string{"Здравствуйте, Ivan!", "отклонена", "05.10.2025"},
+		},
+		{title: "sad: not reviewed", event: statusChanged(english, "not processed")},
+		{title: "sad: opted out", event: statusChanged(optedOut, "processed")},
+		{title: "sad: deactivated", event: statusChanged(deactivated, "processed")},
+		{title: "sad: no email", event: statusChanged(noEmail, "processed")},
+		{title: "sad: deleted user", event: statusChanged(100, "processed")},
+		{title: "sad: other event", event: events.Event{Type: events.EntryCreated, Data: entry.Entry{UserID: english, Status: "processed"}}},
+		{
+			title:       "happy: reminder",
+			event:       remind(english),
+			wantTo:      "english@example.com",
+			wantSubject: "Your course starts soon: Go",
+			wantText:    []string{"Hello, Ivan!", "October 5, 2025", "09:00"},
+		},
+		{
+			title:       "happy: reminder in the language of the user",
+			event:       remind(russian),
+			wantTo:      "russian@example.com",
+			wantSubject: "Скоро начало курса: Go",
+			wantText:    []string{"Напоминаем", "05.10.2025", "09:00"},
+		},
+		{title: "happy: reminder opted out of status changes only", event: remind(optedOut), wantTo: "opted_out@example.com", wantSubject: "Your course starts soon: Go"},
+		{title: "sad: reminders opted out", event: remind(noReminders)},
+		{title: "sad: reminder of a deactivated user", event: remind(deactivated)},
+		{title: "sad: reminder of an entry rejected since", event: reminderOfRejected},
+		{title: "sad: reminder of a deleted entry", event: reminderOfDeleted},
+		{title: "sad: reminder of an entry moved since", event: reminderOfMoved},
+		{title: "sad: reminder of an entry not approved", event: remindOf(newEntry(english, "not processed"))},
+	}
+
+	for _, tc := range testCases {
+		mailer := &recorder{}
+		n := NewNotifier(storage, storage, storage, mailer, nil)
+
+		require.NoError(t, n.Handle(ctx, tc.event), tc.title)
+
+		if tc.wantTo == "" {
+			assert.Empty(t, mailer.sent, tc.title)
+			continue
+		}
+		require.Len(t, mailer.sent, 1, tc.title)
+		msg := mailer.sent[0]
+		assert.Equal(t, tc.wantTo, msg.To, tc.title)
+		assert.Equal(t, tc.wantSubject, msg.Subject, tc.title)
+		for _, text := range tc.wantText {
+			assert.Contains(t, msg.Text, text, tc.title)
+			assert.Contains(t, msg.HTML, text, tc.title)
+		}
+	}
+}
+
+func TestNotifierRetriedThroughRelay(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	u, err := storage.CreateUser(ctx, "ivan", "hash", "<b>Ivan</b>", "", "", "", "ivan@example.com", false)
+	require.NoError(t, err)
+	e, err := storage.CreateEntry(ctx, "Go", time.Now(), u.ID, "card")
+	require.NoError(t, err)
+	_, _, err = storage.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+	require.NoError(t, err)
+
+	mailer := &recorder{err: errors.New("smtp is down")}
+	relay := events.NewRelay(storage, events.RelayConfig{})
+	relay.Handle("mail", NewNotifier(storage, storage, storage, mailer, nil).Handle)
+
+	assert.Error(t, relay.Flush(ctx))
+	assert.Empty(t, mailer.sent)
+
+	mailer.err = nil
+	require.NoError(t, relay.Flush(ctx))
+	require.Len(t, mailer.sent, 1, "the failed email is sent again")
+	assert.Contains(t, mailer.sent[0].HTML, "&lt;b&gt;Ivan&lt;/b&gt;", "HTML is escaped")
+	assert.Contains(t, mailer.sent[0].Text, "<b>Ivan</b>")
+}
+
+func TestNotifierSendsOnce(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	u, err := storage.CreateUser(ctx, "ivan", "hash", "Ivan", "", "", "", "ivan@example.com", false)
+	require.NoError(t, err)
+	e, err := storage.CreateEntry(ctx, "Go", time.Now(), u.ID, "card")
+	require.NoError(t, err)
+	_, _, err = storage.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+	require.NoError(t, err)
+
+	mailer := &recorder{}
+	webhooksDown := true
+	// a new relay starts from the oldest message not deleted, like after
+	// a restart
+	relay := func() *events.Relay {
+		relay := events.NewRelay(storage, events.RelayConfig{})
+		relay.Handle(HandlerName, NewNotifier(storage, storage, storage, mailer, nil).Handle)
+		relay.Handle("webhooks", func(ctx context.Context, e events.Event) error {
+			if webhooksDown {
+				return errors.New("webhooks are down")
+			}
+			return nil
+		})
+		return relay
+	}
+
+	assert.Error(t, relay().Flush(ctx), "the messages are kept for the webhooks")
+	require.Len(t, mailer.sent, 1)
+
+	webhooksDown = false
+	require.NoError(t, relay().Flush(ctx))
+	assert.Len(t, mailer.sent, 1, "the email is not sent again")
+}
+
+func TestNotifierSkipsSameStatus(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	u, err := storage.CreateUser(ctx, "ivan", "hash", "Ivan", "", "", "", "ivan@example.com", false)
+	require.NoError(t, err)
+	e, err := storage.CreateEntry(ctx, "Go", time.Now(), u.ID, "card")
+	require.NoError(t, err)
+	for _, status := range []string{"processed", "processed", "rejected", "rejected"} {
+		_, _, err = storage.UpdateStatusEntry(ctx, e.ID, status, entry.AnyVersion)
+		require.NoError(t, err)
+	}
+
+	mailer := &recorder{}
+	relay := events.NewRelay(storage, events.RelayConfig{})
+	relay.Handle(HandlerName, NewNotifier(storage, storage, storage, mailer, nil).Handle)
+
+	require.NoError(t, relay.Flush(ctx))
+	assert.Len(t, mailer.sent, 2, "the same status again is not emailed")
+}
+
+func TestNotifierDropsRejected(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	u, err := storage.CreateUser(ctx, "ivan", "hash", "Ivan", "", "", "", "ivan@example.com", false)
+	require.NoError(t, err)
+	for _, course := range []string{"Go", "Rust"} {
+		e, err := storage.CreateEntry(ctx, course, time.Now(), u.ID, "card")
+		require.NoError(t, err)
+		_, _, err = storage.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+		require.NoError(t, err)
+	}
+
+	mailer := &rejecting{course: "Go"}
+	relay := events.NewRelay(storage, events.RelayConfig{})
+	relay.Handle("mail", NewNotifier(storage, storage, storage, mailer, nil).Handle)
+
+	require.NoError(t, relay.Flush(ctx), "a rejected email is not sent again")
+	require.Len(t, mailer.sent, 1, "the next email is not held back")
+	assert.Contains(t, mailer.sent[0].Subject, "Rust")
+}
+
+// rejecting is a mailer refusing the emails about a course for good.
+type rejecting struct {
+	recorder
+	course string
+}
+
+func (r *rejecting) Send(ctx context.Context, m mail.Message) error {
+	if strings.HasSuffix(m.Subject, r.course) {
+		return fmt.Errorf("%w: 550 mailbox unavailable", mail.ErrRejected)
+	}
+	return r.recorder.Send(ctx, m)
+}
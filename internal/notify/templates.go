@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"practice-backend/internal/i18n"
+	texttemplate "text/template"
+)
+
+// templates hold the emails as <name>.txt and <name>.html. Texts come
+// from the i18n catalog through {{t "mail.<key>"}}.
+//
+//go:embed templates
+var templates embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.New("").
+			Funcs(texttemplate.FuncMap{"t": translator(i18n.Default)}).
+			ParseFS(templates, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").
+			Funcs(htmltemplate.FuncMap{"t": translator(i18n.Default)}).
+			ParseFS(templates, "templates/*.html"))
+)
+
+func translator(lang i18n.Language) func(key string) string {
+	return func(key string) string {
+		msg, _ := i18n.Message(lang, key)
+		return msg
+	}
+}
+
+// render fills the text and the HTML template of the email in the
+// language.
+func render(name string, lang i18n.Language, data any) (text string, html string, err error) {
+	textTmpl, err := textTemplates.Clone()
+	if err != nil {
+		return "", "", err
+	}
+	var textBuf bytes.Buffer
+	err = textTmpl.Funcs(texttemplate.FuncMap{"t": translator(lang)}).ExecuteTemplate(&textBuf, name+".txt", data)
+	if err != nil {
+		return "", "", err
+	}
+
+	htmlTmpl, err := htmlTemplates.Clone()
+	if err != nil {
+		return "", "", err
+	}
+	var htmlBuf bytes.Buffer
+	err = htmlTmpl.Funcs(htmltemplate.FuncMap{"t": translator(lang)}).ExecuteTemplate(&htmlBuf, name+".html", data)
+	if err != nil {
+		return "", "", err
+	}
+
+	return textBuf.String(), htmlBuf.String(), nil
+}
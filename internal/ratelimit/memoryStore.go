@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets of a single instance. Buckets that are
+// full again are dropped, so idle clients don't take memory.
+type MemoryStore struct {
+	mtx       *sync.Mutex
+	buckets   map[string]*storedBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type storedBucket struct {
+	bucket
+	limit Limit
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mtx:     new(sync.Mutex),
+		buckets: make(map[string]*storedBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &storedBucket{
+			bucket: bucket{tokens: float64(limit.burst()), last: now},
+			limit:  limit,
+		}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+
+	return b.take(limit, now), nil
+}
+
+// Len returns the number of kept buckets.
+func (s *MemoryStore) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.buckets)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		refilled := b.tokens + now.Sub(b.last).Seconds()*float64(b.limit.Requests)/b.limit.Per.Seconds()
+		if refilled >= float64(b.limit.burst()) {
+			delete(s.buckets, key)
+		}
+	}
+}
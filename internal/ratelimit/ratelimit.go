@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New(`rate limit must look like "10/1m"`)
+
+// Limit is a token bucket: it holds up to Burst requests and refills
+// Requests every Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	// Burst defaults to Requests
+	Burst int
+}
+
+// ParseLimit parses "<requests>/<period>", e.g. "10/1m", "5/s" or "100/h".
+// An empty string or "0" is a zero Limit, which means no limiting.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	requestsStr, perStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil || requests <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	if perStr == "s" || perStr == "m" || perStr == "h" {
+		perStr = "1" + perStr
+	}
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	return Limit{Requests: requests, Per: per}, nil
+}
+
+func (l Limit) IsZero() bool {
+	return l.Requests == 0
+}
+
+func (l Limit) String() string {
+	if l.IsZero() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Result tells whether a request may pass and what to answer in the
+// RateLimit-* headers.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit     int
+	Remaining int
+	// ResetAfter is when the bucket is full again
+	ResetAfter time.Duration
+	// RetryAfter is when the next request may pass, zero if it may now
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets, Take refills one and takes a token atomically.
+// A MemoryStore counts per process, so every process behind a load
+// balancer allows the whole limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the state of a token bucket at the moment last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket up to now and takes a token if there is one.
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	burst := float64(limit.burst())
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+	b.last = now
+
+	res := Result{Limit: limit.burst()}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = secondsToDuration((burst - b.tokens) / rate)
+
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
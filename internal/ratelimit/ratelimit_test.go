@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		title     string
+		s         string
+		wantLimit Limit
+		wantErr   error
+	}{
+		{title: "happy: requests per minute", s: "10/1m", wantLimit: Limit{Requests: 10, Per: time.Minute}},
+		{title: "happy: unit without number", s: "5/s", wantLimit: Limit{Requests: 5, Per: time.Second}},
+		{title: "happy: off", s: "0", wantLimit: Limit{}},
+		{title: "happy: empty", s: "", wantLimit: Limit{}},
+		{title: "sad: no period", s: "10", wantErr: ErrInvalidLimit},
+		{title: "sad: zero requests", s: "0/1m", wantErr: ErrInvalidLimit},
+		{title: "sad: bad period", s: "10/week", wantErr: ErrInvalidLimit},
+	}
+
+	for _, tc := range testCases {
+		limit, err := ParseLimit(tc.s)
+
+		assert.ErrorIs(t, err, tc.wantErr, tc.title)
+		assert.Equal(t, tc.wantLimit, limit, tc.title)
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 2, Per: time.Second}
+	ctx := context.Background()
+
+	for i := range 2 {
+		res, err := store.Take(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 1-i, res.Remaining)
+	}
+
+	res, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, time.Second, res.ResetAfter)
+
+	res, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "other keys have their own bucket")
+
+	now = now.Add(500 * time.Millisecond)
+	res, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "a token is refilled")
+
+	now = now.Add(2 * sweepInterval)
+	_, err = store.Take(ctx, "c", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.Len(), "full buckets are swept")
+}
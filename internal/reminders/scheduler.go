@@ -0,0 +1,181 @@
+package reminders
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"os"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/lease"
+	"practice-backend/internal/models/reminder"
+	"slices"
+	"time"
+)
+
+const (
+	DefaultInterval = time.Minute
+
+	// LeaseName is the lease a replica needs to look for due reminders
+	LeaseName = "reminders"
+	// approvedStatus is the status of the entries reminded of
+	approvedStatus = "processed"
+)
+
+// DefaultOffsets remind three days and two hours before the course start.
+var DefaultOffsets = []time.Duration{72 * time.Hour, 2 * time.Hour}
+
+type Config struct {
+	// Offsets are how long before the course start the reminders are due
+	Offsets []time.Duration
+	// Location and StartTime place the course start: the entry date at
+	// StartTime, the time of day, in Location. UTC midnight by default.
+	Location  *time.Location
+	StartTime time.Duration
+	// Interval is how often due reminders are looked for
+	Interval time.Duration
+	// LeaseTTL is how long the lease outlives a replica that stopped
+	// renewing it, three intervals by default
+	LeaseTTL time.Duration
+	// Holder names the replica in the lease, the host name and a random
+	// suffix by default
+	Holder string
+	Logger *slog.Logger
+}
+
+// Repo records the reminders and hands out the lease.
+type Repo interface {
+	reminder.ReminderRepo
+	lease.LeaseRepo
+}
+
+// Scheduler records the reminders of approved entries when they are due,
+// the relay hands them over to the notifier. A reminder is recorded once,
+// so restarts don't send it again, and only the holder of the lease looks
+// for due reminders. Replicas are kept apart only by a LeaseRepo they
+// share.
+type Scheduler struct {
+	entries entry.EntryRepo
+	repo    Repo
+	cfg     Config
+	now     func() time.Time
+}
+
+func NewScheduler(entries entry.EntryRepo, repo Repo, cfg Config) *Scheduler {
+	if len(cfg.Offsets) == 0 {
+		cfg.Offsets = DefaultOffsets
+	}
+	// the nearest offset first, see due
+	cfg.Offsets = slices.Sorted(slices.Values(cfg.Offsets))
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 3 * cfg.Interval
+	}
+	if cfg.Holder == "" {
+		host, _ := os.Hostname()
+		cfg.Holder = host + "-" + rand.Text()[:8]
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &Scheduler{
+		entries: entries,
+		repo:    repo,
+		cfg:     cfg,
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run schedules the due reminders while the replica holds the lease,
+// until ctx is done. The lease is released on return.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer s.repo.ReleaseLease(context.WithoutCancel(ctx), LeaseName, s.cfg.Holder)
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	lastPrune := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		leased, err := s.repo.AcquireLease(ctx, LeaseName, s.cfg.Holder, s.cfg.LeaseTTL)
+		if err != nil {
+			s.cfg.Logger.Error("acquire reminders lease", "error", err)
+			continue
+		}
+		if !leased {
+			continue
+		}
+
+		if _, err := s.Schedule(ctx); err != nil {
+			s.cfg.Logger.Error("schedule reminders", "error", err)
+		}
+
+		// records of started courses are never needed again
+		if now := s.now(); now.Sub(lastPrune) >= time.Hour {
+			lastPrune = now
+			if _, err := s.repo.PruneReminders(ctx, now); err != nil {
+				s.cfg.Logger.Error("prune reminders", "error", err)
+			}
+		}
+	}
+}
+
+// Schedule records the due reminders and returns how many it recorded.
+func (s *Scheduler) Schedule(ctx context.Context) (int, error) {
+	entries, err := s.entries.GetEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	var recorded int
+	for _, en := range entries {
+		if en.Status != approvedStatus {
+			continue
+		}
+
+		startsAt := en.StartsAt(s.cfg.Location, s.cfg.StartTime)
+		before, ok := s.due(now, startsAt)
+		if !ok {
+			continue
+		}
+
+		ok, err := s.repo.RecordReminder(ctx, reminder.Reminder{Entry: en, Before: before, StartsAt: startsAt})
+		if err != nil {
+			return recorded, err
+		}
+		if ok {
+			recorded++
+			s.cfg.Logger.Info("reminder scheduled", "entry_id", en.ID, "before", before)
+		}
+	}
+
+	return recorded, nil
+}
+
+// due returns the offset of the reminder due at now for a course starting
+// at startsAt: the nearest offset whose time has come. An entry approved
+// late gets one reminder instead of all the missed ones.
+func (s *Scheduler) due(now time.Time, startsAt time.Time) (time.Duration, bool) {
+	if !now.Before(startsAt) {
+		return 0, false
+	}
+
+	for _, before := range s.cfg.Offsets {
+		if !now.Before(startsAt.Add(-before)) {
+			return before, true
+		}
+	}
+
+	return 0, false
+}
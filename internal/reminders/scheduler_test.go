@@ -0,0 +1,139 @@
+package reminders
+
+import (
+	"context"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/storage/inmem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// the course starts on October 10 at 9:00 in Moscow, 6:00 UTC
+	date := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	approved, err := storage.CreateEntry(ctx, "go", date, 1, "card")
+	require.NoError(t, err)
+	_, _, err = storage.UpdateStatusEntry(ctx, approved.ID, "processed", approved.Version)
+	require.NoError(t, err)
+	_, err = storage.CreateEntry(ctx, "go", date, 2, "card")
+	require.NoError(t, err)
+
+	s := NewScheduler(storage, storage, Config{
+		Offsets:   []time.Duration{2 * time.Hour, 72 * time.Hour},
+		Location:  moscow,
+		StartTime: 9 * time.Hour,
+	})
+	startsAt := time.Date(2025, 10, 10, 6, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		title      string
+		now        time.Time
+		want       int
+		wantBefore time.Duration
+	}{
+		{title: "sad: too early", now: startsAt.Add(-73 * time.Hour), want: 0},
+		{title: "happy: first reminder due", now: startsAt.Add(-72 * time.Hour), want: 1, wantBefore: 72 * time.Hour},
+		{title: "sad: sent already", now: startsAt.Add(-71 * time.Hour), want: 0},
+		{title: "happy: second reminder due", now: startsAt.Add(-time.Hour), want: 1, wantBefore: 2 * time.Hour},
+		{title: "sad: course started", now: startsAt, want: 0},
+	}
+
+	after := 0
+	for _, tc := range testCases {
+		s.now = func() time.Time { return tc.now }
+
+		n, err := s.Schedule(ctx)
+		assert.NoError(t, err, tc.title)
+		assert.Equal(t, tc.want, n, tc.title)
+
+		var reminders []outbox.Message
+		messages, _ := storage.PendingMessages(ctx, after, 100)
+		for _, m := range messages {
+			after = m.ID
+			if m.Type == outbox.EntryReminder {
+				reminders = append(reminders, m)
+			}
+		}
+		require.Len(t, reminders, tc.want, tc.title)
+		if tc.want > 0 {
+			assert.Equal(t, tc.wantBefore, reminders[0].Reminder.Before, tc.title)
+			assert.Equal(t, approved.ID, reminders[0].Reminder.Entry.ID, tc.title)
+			assert.True(t, startsAt.Equal(reminders[0].Reminder.StartsAt), tc.title)
+		}
+	}
+}
+
+func TestScheduleLateApproval(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	date := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	en, err := storage.CreateEntry(ctx, "go", date, 1, "card")
+	require.NoError(t, err)
+	_, _, err = storage.UpdateStatusEntry(ctx, en.ID, "processed", en.Version)
+	require.NoError(t, err)
+
+	s := NewScheduler(storage, storage, Config{Offsets: []time.Duration{72 * time.Hour, 2 * time.Hour}})
+	s.now = func() time.Time { return date.Add(-time.Hour) }
+
+	n, err := s.Schedule(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "only the nearest missed reminder is sent")
+
+	messages, _ := storage.PendingMessages(ctx, 0, 100)
+	last := messages[len(messages)-1]
+	assert.Equal(t, outbox.EntryReminder, last.Type)
+	assert.Equal(t, 2*time.Hour, last.Reminder.Before)
+}
+
+func TestRunNeedsLease(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := t.Context()
+
+	date := time.Now().UTC().Add(24 * time.Hour)
+	en, err := storage.CreateEntry(ctx, "go", date, 1, "card")
+	require.NoError(t, err)
+	_, _, err = storage.UpdateStatusEntry(ctx, en.ID, "processed", en.Version)
+	require.NoError(t, err)
+
+	ok, err := storage.AcquireLease(ctx, LeaseName, "other", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	s := NewScheduler(storage, storage, Config{
+		Offsets:  []time.Duration{72 * time.Hour},
+		Interval: time.Millisecond,
+		Holder:   "this",
+	})
+	run := func() int {
+		runCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		require.NoError(t, s.Run(runCtx))
+
+		var reminders int
+		messages, _ := storage.PendingMessages(ctx, 0, 100)
+		for _, m := range messages {
+			if m.Type == outbox.EntryReminder {
+				reminders++
+			}
+		}
+		return reminders
+	}
+
+	assert.Equal(t, 0, run(), "nothing is scheduled without the lease")
+
+	require.NoError(t, storage.ReleaseLease(ctx, LeaseName, "other"))
+	assert.Equal(t, 1, run(), "scheduled once the lease is free")
+
+	ok, _ = storage.AcquireLease(ctx, LeaseName, "other", time.Hour)
+	assert.True(t, ok, "the lease is released when Run returns")
+}
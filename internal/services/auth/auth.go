@@ -4,25 +4,40 @@ import (
 	"context"
 	"errors"
 	"practice-backend/internal/lib/jwt"
+	"practice-backend/internal/metrics"
 	"practice-backend/internal/models/user"
 	"practice-backend/internal/storage/inmem"
 	"time"
 
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidToken       = errors.New("invalid token")
+	ErrUserDeactivated    = errors.New("user is deactivated")
 )
 
+const (
+	tokenTTL = 24 * time.Hour
+	// maxSessionAge bounds refreshing: tokens are refreshed up to this
+	// long after the login, then the password is needed again
+	maxSessionAge = 7 * 24 * time.Hour
+)
+
+var tracer = otel.Tracer("practice-backend/internal/services/auth")
+
 type Auth struct {
 	userRepo user.UserRepo
+	metrics  *metrics.Metrics
 }
 
-func NewAuth(userRepo user.UserRepo) *Auth {
+// NewAuth creates the auth service, m may be nil.
+func NewAuth(userRepo user.UserRepo, m *metrics.Metrics) *Auth {
 	return &Auth{
 		userRepo: userRepo,
+		metrics:  m,
 	}
 }
 
@@ -30,7 +45,10 @@ func (a *Auth) Register(
 	ctx context.Context,
 	user user.User,
 ) (userID int, err error) {
-	passHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	ctx, span := tracer.Start(ctx, "auth.Register")
+	defer span.End()
+
+	passHash, err := hashPassword(ctx, user.Password)
 	if err != nil {
 		return -1, err
 	}
@@ -49,6 +67,8 @@ func (a *Auth) Register(
 		return -1, err
 	}
 
+	a.metrics.IncRegistration()
+
 	return newUser.ID, nil
 }
 
@@ -57,22 +77,90 @@ func (a *Auth) Login(
 	login string,
 	password string,
 ) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "auth.Login")
+	defer span.End()
+
 	user, err := a.userRepo.GetUserByLogin(ctx, login)
 	if err != nil {
+		a.metrics.IncLogin(metrics.LoginFailure)
 		return "", ErrInvalidCredentials
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	if err = comparePassword(ctx, user.Password, password); err != nil {
+		a.metrics.IncLogin(metrics.LoginFailure)
 		return "", ErrInvalidCredentials
 	}
 
-	return jwt.NewToken(user, time.Hour*24)
+	if user.Deactivated {
+		a.metrics.IncLogin(metrics.LoginDeactivated)
+		return "", ErrUserDeactivated
+	}
+
+	a.metrics.IncLogin(metrics.LoginSuccess)
+
+	return jwt.NewToken(user, time.Now(), tokenTTL)
+}
+
+// RefreshToken issues a new token for a user who still has a valid one
+// from a login at authTime. The new token expires maxSessionAge after the
+// login at the latest, so a stolen token can't be refreshed forever.
+func (a *Auth) RefreshToken(
+	ctx context.Context,
+	userID int,
+	authTime time.Time,
+) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "auth.RefreshToken")
+	defer span.End()
+
+	sessionLeft := time.Until(authTime.Add(maxSessionAge))
+	if sessionLeft <= 0 {
+		return "", ErrInvalidToken
+	}
+
+	user, err := a.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	if user.Deactivated {
+		return "", ErrUserDeactivated
+	}
+
+	return jwt.NewToken(user, authTime, min(tokenTTL, sessionLeft))
+}
+
+// Authenticate checks that the user of a valid token may still use the
+// API: tokens of deleted users are invalid, deactivated users are
+// rejected until they are reactivated.
+func (a *Auth) Authenticate(
+	ctx context.Context,
+	userID int,
+) error {
+	ctx, span := tracer.Start(ctx, "auth.Authenticate")
+	defer span.End()
+
+	user, err := a.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, inmem.ErrUserNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if user.Deactivated {
+		return ErrUserDeactivated
+	}
+
+	return nil
 }
 
 func (a *Auth) IsAdmin(
 	ctx context.Context,
 	userID int,
 ) (bool, error) {
+	ctx, span := tracer.Start(ctx, "auth.IsAdmin")
+	defer span.End()
+
 	user, err := a.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, inmem.ErrUserNotFound) {
@@ -81,7 +169,7 @@ func (a *Auth) IsAdmin(
 		return false, ErrInvalidCredentials
 	}
 
-	if user.IsAdmin {
+	if user.IsAdmin && !user.Deactivated {
 		return true, nil
 	}
 	return false, nil
@@ -97,3 +185,94 @@ func (a *Auth) CreateAdminUser(ctx context.Context, login string, password strin
 
 	return err
 }
+
+// ChangePassword sets a new password for the user after checking
+// the current one.
+func (a *Auth) ChangePassword(
+	ctx context.Context,
+	userID int,
+	currentPassword string,
+	newPassword string,
+) error {
+	ctx, span := tracer.Start(ctx, "auth.ChangePassword")
+	defer span.End()
+
+	if err := a.checkPassword(ctx, userID, currentPassword); err != nil {
+		return err
+	}
+
+	passHash, err := hashPassword(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+
+	return a.userRepo.ChangePassword(ctx, userID, string(passHash))
+}
+
+// DeleteAccount removes the user after checking their password.
+// deleteData runs between the check and the removal, so the data of the
+// user is gone before the user and nothing is left without an owner.
+func (a *Auth) DeleteAccount(
+	ctx context.Context,
+	userID int,
+	password string,
+	deleteData func(ctx context.Context) error,
+) error {
+	ctx, span := tracer.Start(ctx, "auth.DeleteAccount")
+	defer span.End()
+
+	if err := a.checkPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	if err := deleteData(ctx); err != nil {
+		return err
+	}
+
+	return a.userRepo.DeleteUser(ctx, userID)
+}
+
+func (a *Auth) checkPassword(ctx context.Context, userID int, password string) error {
+	user, err := a.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err = comparePassword(ctx, user.Password, password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password for the user without checking
+// the current one. It is meant for operators, not for HTTP handlers.
+func (a *Auth) ResetPassword(ctx context.Context, login string, newPassword string) error {
+	ctx, span := tracer.Start(ctx, "auth.ResetPassword")
+	defer span.End()
+
+	user, err := a.userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	passHash, err := hashPassword(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+
+	return a.userRepo.ChangePassword(ctx, user.ID, string(passHash))
+}
+
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+func comparePassword(ctx context.Context, passHash string, password string) error {
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	return bcrypt.CompareHashAndPassword([]byte(passHash), []byte(password))
+}
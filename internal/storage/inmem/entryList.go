@@ -4,19 +4,23 @@ import (
 	"context"
 	"errors"
 	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/outbox"
 	"practice-backend/internal/storage/inmem/ilist"
 	"sync"
 	"time"
 )
 
 var (
-	ErrEntryNotFound = errors.New("entry not found")
+	ErrEntryNotFound        = errors.New("entry not found")
+	ErrEntryVersionMismatch = errors.New("entry was changed by another request")
 )
 
 // Concurrent-Use
 type EntryList struct {
 	list ilist.List[entry.Entry]
-	mtx  *sync.Mutex
+	// outbox gets the events of the changes, nil outside of Storage
+	outbox *Outbox
+	mtx    *sync.Mutex
 }
 
 func NewEntryList() EntryList {
@@ -27,6 +31,9 @@ func NewEntryList() EntryList {
 }
 
 func (el *EntryList) GetEntries(ctx context.Context) ([]entry.Entry, error) {
+	el.mtx.Lock()
+	defer el.mtx.Unlock()
+
 	return el.list.GetData(), nil
 }
 
@@ -44,18 +51,29 @@ func (el *EntryList) GetEntryByID(ctx context.Context, id int) (entry.Entry, err
 	return *e, nil
 }
 
-func (el *EntryList) UpdateStatusEntry(ctx context.Context, id int, status string) (entry.Entry, error) {
-	e, err := el.GetEntryByID(ctx, id)
-	if err != nil {
-		return entry.Entry{}, err
-	}
-
+func (el *EntryList) UpdateStatusEntry(ctx context.Context, id int, status string, version int) (entry.Entry, string, error) {
 	el.mtx.Lock()
 	defer el.mtx.Unlock()
 
+	e, err := el.entryAtVersion(id, version)
+	if err != nil {
+		return entry.Entry{}, "", err
+	}
+
+	previous := e.Status
+	if previous == status {
+		// nothing changes, so there is no new version and no event
+		return e, previous, nil
+	}
 	e.UpdateStatus(status)
 
-	return el.list.UpdateData(id, e)
+	e, err = el.list.UpdateData(id, e)
+	if err != nil {
+		return entry.Entry{}, "", err
+	}
+	el.outbox.add(outbox.Message{Type: outbox.EntryStatusChanged, UserID: e.UserID, Entry: &e})
+
+	return e, previous, nil
 }
 
 func (el *EntryList) CreateEntry(
@@ -75,20 +93,45 @@ func (el *EntryList) CreateEntry(
 	if err != nil {
 		return entry.Entry{}, err
 	}
+	el.outbox.add(outbox.Message{Type: outbox.EntryCreated, UserID: e.UserID, Entry: &e})
 
 	return e, nil
 }
 
-func (el *EntryList) DeleteEntry(ctx context.Context, id int) error {
+func (el *EntryList) DeleteEntry(ctx context.Context, id int, version int) error {
 	el.mtx.Lock()
 	defer el.mtx.Unlock()
 
+	e, err := el.entryAtVersion(id, version)
+	if err != nil {
+		return err
+	}
+
 	if err := el.list.DeleteData(id); err != nil {
 		if errors.Is(err, ilist.ErrDataNotFound) {
 			return ErrEntryNotFound
 		}
 		return err
 	}
+	el.outbox.add(outbox.Message{Type: outbox.EntryDeleted, UserID: e.UserID, Entry: &e})
 
 	return nil
 }
+
+// entryAtVersion returns the entry if it is at version. The caller must
+// hold el.mtx.
+func (el *EntryList) entryAtVersion(id int, version int) (entry.Entry, error) {
+	e, err := el.list.GetDataByID(id)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return entry.Entry{}, ErrEntryNotFound
+		}
+		return entry.Entry{}, err
+	}
+
+	if version != entry.AnyVersion && e.Version != version {
+		return entry.Entry{}, ErrEntryVersionMismatch
+	}
+
+	return *e, nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetEntry(t *testing.T) {
@@ -68,11 +69,19 @@ func TestDeleteEntry(t *testing.T) {
 	testCases := []struct {
 		title   string
 		id      int
+		version int
 		wantErr string
 	}{
+		{
+			title:   "sad: delete stale version",
+			id:      0,
+			version: 2,
+			wantErr: "changed by another request",
+		},
 		{
 			title:   "happy: delete existing data",
 			id:      0,
+			version: 1,
 			wantErr: "",
 		},
 		{
@@ -88,7 +97,7 @@ func TestDeleteEntry(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		err := l.DeleteEntry(context.Background(), tc.id)
+		err := l.DeleteEntry(context.Background(), tc.id, tc.version)
 		if tc.wantErr != "" {
 			assert.Contains(t, err.Error(), tc.wantErr)
 		} else {
@@ -97,6 +106,32 @@ func TestDeleteEntry(t *testing.T) {
 	}
 }
 
+func TestDeleteEntryKeepsIDs(t *testing.T) {
+	l := NewEntryList()
+
+	for range 3 {
+		_, err := l.CreateEntry(t.Context(), "some", time.Now(), 0, "card")
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, l.DeleteEntry(t.Context(), 1, entry.AnyVersion))
+
+	_, err := l.GetEntryByID(t.Context(), 1)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+
+	e, err := l.GetEntryByID(t.Context(), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, e.ID)
+
+	created, err := l.CreateEntry(t.Context(), "some", time.Now(), 0, "card")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, created.ID)
+
+	entries, err := l.GetEntries(t.Context())
+	assert.Nil(t, err)
+	assert.Len(t, entries, 3)
+}
+
 func TestUpdateEntryStatus(t *testing.T) {
 	l := NewEntryList()
 
@@ -112,12 +147,21 @@ func TestUpdateEntryStatus(t *testing.T) {
 		title   string
 		id      int
 		status  string
+		version int
 		wantErr string
 	}{
+		{
+			title:   "sad: mark stale version",
+			id:      0,
+			status:  "processed",
+			version: 2,
+			wantErr: "changed by another request",
+		},
 		{
 			title:   "happy: mark existing data",
 			id:      0,
 			status:  "processed",
+			version: 1,
 			wantErr: "",
 		},
 		{
@@ -135,13 +179,15 @@ func TestUpdateEntryStatus(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		markedEntry, err := l.UpdateStatusEntry(t.Context(), tc.id, tc.status)
+		markedEntry, previous, err := l.UpdateStatusEntry(t.Context(), tc.id, tc.status, tc.version)
 		if tc.wantErr != "" {
 			assert.Contains(t, err.Error(), tc.wantErr, tc.title)
 			continue
 		}
 		assert.Nil(t, err)
 
+		assert.Equal(t, tc.version+1, markedEntry.Version, tc.title)
+		assert.Equal(t, "not processed", previous, tc.title)
 		if assert.Equal(t, "processed", markedEntry.Status) {
 			entry, _ := l.GetEntryByID(context.TODO(), tc.id)
 			assert.Equal(t, "processed", entry.Status, tc.title)
@@ -149,6 +195,30 @@ func TestUpdateEntryStatus(t *testing.T) {
 	}
 }
 
+func TestUpdateEntrySameStatus(t *testing.T) {
+	s := NewStorage()
+	ctx := t.Context()
+
+	e, err := s.CreateEntry(ctx, "Go", time.Now(), 0, "card")
+	require.NoError(t, err)
+	e, _, err = s.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+	require.NoError(t, err)
+	before, err := s.PendingMessages(ctx, 0, 100)
+	require.NoError(t, err)
+
+	_, _, err = s.UpdateStatusEntry(ctx, e.ID, "processed", e.Version+1)
+	assert.ErrorIs(t, err, ErrEntryVersionMismatch, "the version is still checked")
+
+	same, previous, err := s.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "processed", previous)
+	assert.Equal(t, e, same, "the version is not bumped")
+
+	after, err := s.PendingMessages(ctx, 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "no event is recorded")
+}
+
 func TestGetEntries(t *testing.T) {
 	l := NewEntryList()
 
@@ -0,0 +1,65 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FileWriter periodically saves the storage to a file, so the data
+// survives restarts.
+type FileWriter struct {
+	storage  *Storage
+	path     string
+	interval time.Duration
+
+	mtx     *sync.Mutex
+	lastErr error
+}
+
+func NewFileWriter(storage *Storage, path string, interval time.Duration) *FileWriter {
+	return &FileWriter{
+		storage:  storage,
+		path:     path,
+		interval: interval,
+		mtx:      new(sync.Mutex),
+	}
+}
+
+// Run saves the storage every interval until ctx is done, then saves it
+// one last time.
+func (fw *FileWriter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(fw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fw.Flush()
+		case <-ticker.C:
+			fw.Flush()
+		}
+	}
+}
+
+// Flush saves the storage right away.
+func (fw *FileWriter) Flush() error {
+	fw.mtx.Lock()
+	defer fw.mtx.Unlock()
+
+	fw.lastErr = fw.storage.SaveFile(fw.path)
+	return fw.lastErr
+}
+
+// LastErr returns the error of the last save, if any.
+func (fw *FileWriter) LastErr() error {
+	fw.mtx.Lock()
+	defer fw.mtx.Unlock()
+
+	return fw.lastErr
+}
+
+// CheckHealth reports the error of the last save.
+func (fw *FileWriter) CheckHealth(ctx context.Context) error {
+	return fw.LastErr()
+}
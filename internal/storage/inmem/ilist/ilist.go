@@ -2,7 +2,6 @@ package ilist
 
 import (
 	"errors"
-	"slices"
 )
 
 var (
@@ -11,22 +10,35 @@ var (
 	ErrInvalidID        = errors.New("invalid id")
 )
 
+// List keeps data addressed by its index. Deleted items leave a tombstone,
+// so the IDs of the remaining items never shift.
 type List[V any] struct {
-	list []V
+	list    []V
+	deleted map[int]struct{}
 }
 
 func NewList[V any]() List[V] {
 	return List[V]{
-		list: make([]V, 0),
+		list:    make([]V, 0),
+		deleted: make(map[int]struct{}),
 	}
 }
 
+// GetLen returns the number of slots ever allocated, deleted ones included.
+// It is safe to use as the next free ID.
 func (l *List[V]) GetLen() int {
 	return len(l.list)
 }
 
 func (l *List[V]) GetData() []V {
-	return l.list
+	data := make([]V, 0, len(l.list)-len(l.deleted))
+	for id, v := range l.list {
+		if _, ok := l.deleted[id]; ok {
+			continue
+		}
+		data = append(data, v)
+	}
+	return data
 }
 
 func (l *List[V]) GetDataByID(id int) (*V, error) {
@@ -36,6 +48,9 @@ func (l *List[V]) GetDataByID(id int) (*V, error) {
 	if id >= len(l.list) {
 		return nil, ErrDataNotFound
 	}
+	if _, ok := l.deleted[id]; ok {
+		return nil, ErrDataNotFound
+	}
 	return &l.list[id], nil
 }
 
@@ -58,7 +73,32 @@ func (l *List[V]) DeleteData(id int) error {
 		return err
 	}
 
-	l.list = slices.Delete(l.list, id, id+1)
+	l.list[id] = *new(V)
+	l.deleted[id] = struct{}{}
+
+	return nil
+}
+
+// Restore puts data under the given ID, growing the list with deleted
+// slots when needed. It is used to load a saved list back.
+func (l *List[V]) Restore(id int, data V) error {
+	if id < 0 {
+		return ErrInvalidID
+	}
+
+	l.Reserve(id + 1)
+
+	l.list[id] = data
+	delete(l.deleted, id)
 
 	return nil
 }
+
+// Reserve grows the list with deleted slots up to length n, so the IDs
+// below n are never handed out again.
+func (l *List[V]) Reserve(n int) {
+	for len(l.list) < n {
+		l.deleted[len(l.list)] = struct{}{}
+		l.list = append(l.list, *new(V))
+	}
+}
@@ -1,13 +1,63 @@
 package inmem
 
+import (
+	"context"
+	"sync"
+	"time"
+)
+
 type Storage struct {
 	EntryList
 	UserList
+	WebhookList
+	ReminderList
+	Leases
+	*Outbox
 }
 
+// NewStorage records the domain events of the users and entries, and the
+// reminders to send, in its outbox.
 func NewStorage() *Storage {
-	return &Storage{
-		EntryList: NewEntryList(),
-		UserList:  NewUserList(),
+	s := &Storage{
+		EntryList:    NewEntryList(),
+		UserList:     NewUserList(),
+		WebhookList:  NewWebhookList(),
+		ReminderList: NewReminderList(),
+		Leases:       NewLeases(),
+		Outbox:       NewOutbox(),
 	}
+	s.EntryList.outbox = s.Outbox
+	s.UserList.outbox = s.Outbox
+	s.ReminderList.outbox = s.Outbox
+
+	return s
+}
+
+// healthRetry is how long CheckHealth waits before it tries a lock held
+// by someone else again.
+const healthRetry = time.Millisecond
+
+// CheckHealth makes sure the storage is not stuck holding its locks. It
+// only tries the locks, so a stuck lock doesn't keep it waiting past ctx.
+func (s *Storage) CheckHealth(ctx context.Context) error {
+	locks := []*sync.Mutex{
+		s.UserList.mtx,
+		s.EntryList.mtx,
+		s.WebhookList.mtx,
+		s.ReminderList.mtx,
+		s.Outbox.mtx,
+	}
+
+	for _, mtx := range locks {
+		for !mtx.TryLock() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(healthRetry):
+			}
+		}
+		mtx.Unlock()
+	}
+
+	return nil
 }
@@ -0,0 +1,26 @@
+package inmem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHealth(t *testing.T) {
+	s := NewStorage()
+
+	assert.NoError(t, s.CheckHealth(t.Context()))
+
+	s.EntryList.mtx.Lock()
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.CheckHealth(ctx), context.DeadlineExceeded, "a stuck lock fails the check")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.EntryList.mtx.Unlock()
+	}()
+	assert.NoError(t, s.CheckHealth(t.Context()), "a lock held for a while is waited for")
+}
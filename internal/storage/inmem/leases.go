@@ -0,0 +1,52 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// Leases hand out named leases. They live in memory only, so they keep
+// apart the workers of one process but don't coordinate replicas.
+// Concurrent-Use
+type Leases struct {
+	leases map[string]lease
+	mtx    *sync.Mutex
+}
+
+func NewLeases() Leases {
+	return Leases{
+		leases: make(map[string]lease),
+		mtx:    new(sync.Mutex),
+	}
+}
+
+func (l *Leases) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	if current, ok := l.leases[name]; ok && current.holder != holder && now.Before(current.expiresAt) {
+		return false, nil
+	}
+
+	l.leases[name] = lease{holder: holder, expiresAt: now.Add(ttl)}
+
+	return true, nil
+}
+
+func (l *Leases) ReleaseLease(ctx context.Context, name string, holder string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if current, ok := l.leases[name]; ok && current.holder == holder {
+		delete(l.leases, name)
+	}
+
+	return nil
+}
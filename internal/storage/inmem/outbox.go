@@ -0,0 +1,101 @@
+package inmem
+
+import (
+	"cmp"
+	"context"
+	"practice-backend/internal/models/outbox"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Outbox keeps the domain events recorded by the lists until the relay
+// deletes them. Lists add to it while holding their own lock, so the lock
+// order is the list first, then the outbox.
+// Concurrent-Use
+type Outbox struct {
+	// pending messages, oldest first, so their IDs are sorted
+	pending []outbox.Message
+	// handled has the names of the handlers that marked a pending message
+	// by its ID
+	handled map[int][]string
+	nextID  int
+	mtx     *sync.Mutex
+}
+
+func NewOutbox() *Outbox {
+	return &Outbox{
+		handled: make(map[int][]string),
+		nextID:  1,
+		mtx:     new(sync.Mutex),
+	}
+}
+
+// add records a message of the change made by the caller. The caller must
+// hold the lock of the changed list. A nil outbox records nothing, for
+// lists used on their own.
+func (o *Outbox) add(m outbox.Message) {
+	if o == nil {
+		return
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	m.ID = o.nextID
+	o.nextID++
+	if m.Time.IsZero() {
+		m.Time = time.Now().UTC()
+	}
+
+	o.pending = append(o.pending, m)
+}
+
+func (o *Outbox) PendingMessages(ctx context.Context, afterID int, limit int) ([]outbox.Message, error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	start, _ := slices.BinarySearchFunc(o.pending, afterID+1, func(m outbox.Message, id int) int {
+		return cmp.Compare(m.ID, id)
+	})
+	end := min(start+limit, len(o.pending))
+
+	return slices.Clone(o.pending[start:end]), nil
+}
+
+func (o *Outbox) DeleteMessages(ctx context.Context, throughID int) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	n, _ := slices.BinarySearchFunc(o.pending, throughID+1, func(m outbox.Message, id int) int {
+		return cmp.Compare(m.ID, id)
+	})
+	for _, m := range o.pending[:n] {
+		delete(o.handled, m.ID)
+	}
+	o.pending = slices.Delete(o.pending, 0, n)
+
+	return nil
+}
+
+func (o *Outbox) Handled(ctx context.Context, name string, messageID int) (bool, error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	return slices.Contains(o.handled[messageID], name), nil
+}
+
+func (o *Outbox) MarkHandled(ctx context.Context, name string, messageID int) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	_, pending := slices.BinarySearchFunc(o.pending, messageID, func(m outbox.Message, id int) int {
+		return cmp.Compare(m.ID, id)
+	})
+	// a deleted message is never relayed again
+	if pending && !slices.Contains(o.handled[messageID], name) {
+		o.handled[messageID] = append(o.handled[messageID], name)
+	}
+
+	return nil
+}
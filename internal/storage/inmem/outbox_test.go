@@ -0,0 +1,183 @@
+package inmem
+
+import (
+	"bytes"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/models/user"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRecordsChanges(t *testing.T) {
+	s := NewStorage()
+	ctx := t.Context()
+
+	u, err := s.CreateUser(ctx, "ivan", "hash", "Ivan", "", "", "", "", false)
+	require.NoError(t, err)
+	e, err := s.CreateEntry(ctx, "Go", time.Now(), u.ID, "card")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		title    string
+		change   func() error
+		wantType string
+	}{
+		{
+			title: "sad: taken login is not recorded",
+			change: func() error {
+				_, err := s.CreateUser(ctx, "ivan", "", "", "", "", "", "", false)
+				return err
+			},
+		},
+		{
+			title: "happy: user updated",
+			change: func() error {
+				_, err := s.UpdateUser(ctx, user.User{ID: u.ID, Login: "ivan", Name: "Ivan"})
+				return err
+			},
+			wantType: outbox.UserUpdated,
+		},
+		{
+			title: "sad: stale version is not recorded",
+			change: func() error {
+				_, _, err := s.UpdateStatusEntry(ctx, e.ID, "processed", e.Version+1)
+				return err
+			},
+		},
+		{
+			title: "happy: status changed",
+			change: func() error {
+				_, _, err := s.UpdateStatusEntry(ctx, e.ID, "processed", e.Version)
+				return err
+			},
+			wantType: outbox.EntryStatusChanged,
+		},
+		{
+			title: "sad: same status is not recorded",
+			change: func() error {
+				_, _, err := s.UpdateStatusEntry(ctx, e.ID, "processed", entry.AnyVersion)
+				return err
+			},
+		},
+		{
+			title:    "happy: entry deleted",
+			change:   func() error { return s.DeleteEntry(ctx, e.ID, entry.AnyVersion) },
+			wantType: outbox.EntryDeleted,
+		},
+		{
+			title:    "happy: user deleted",
+			change:   func() error { return s.DeleteUser(ctx, u.ID) },
+			wantType: outbox.UserDeleted,
+		},
+		{
+			title:  "sad: password change is not recorded",
+			change: func() error { return s.ChangePassword(ctx, u.ID, "new") },
+		},
+	}
+
+	wantTypes := []string{outbox.UserRegistered, outbox.EntryCreated}
+	for _, tc := range testCases {
+		tc.change()
+		if tc.wantType != "" {
+			wantTypes = append(wantTypes, tc.wantType)
+		}
+
+		messages, err := s.PendingMessages(ctx, 0, 100)
+		require.NoError(t, err, tc.title)
+
+		var types []string
+		for _, m := range messages {
+			types = append(types, m.Type)
+			if m.User != nil {
+				assert.Empty(t, m.User.Password, tc.title)
+			}
+			assert.Equal(t, u.ID, m.UserID, tc.title)
+		}
+		assert.Equal(t, wantTypes, types, tc.title)
+	}
+}
+
+func TestOutboxPendingMessages(t *testing.T) {
+	s := NewStorage()
+	ctx := t.Context()
+
+	for range 4 {
+		_, err := s.CreateEntry(ctx, "Go", time.Now(), 0, "card")
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.DeleteMessages(ctx, 1))
+
+	testCases := []struct {
+		title   string
+		afterID int
+		limit   int
+		wantIDs []int
+	}{
+		{title: "happy: from the oldest", afterID: 0, limit: 10, wantIDs: []int{2, 3, 4}},
+		{title: "happy: deleted ones are skipped", afterID: 1, limit: 10, wantIDs: []int{2, 3, 4}},
+		{title: "happy: after an ID", afterID: 2, limit: 10, wantIDs: []int{3, 4}},
+		{title: "happy: limited", afterID: 0, limit: 2, wantIDs: []int{2, 3}},
+		{title: "sad: none after the last", afterID: 4, limit: 10},
+	}
+
+	for _, tc := range testCases {
+		messages, err := s.PendingMessages(ctx, tc.afterID, tc.limit)
+		require.NoError(t, err, tc.title)
+
+		var ids []int
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		assert.Equal(t, tc.wantIDs, ids, tc.title)
+	}
+}
+
+func TestOutboxMarkHandled(t *testing.T) {
+	s := NewStorage()
+	ctx := t.Context()
+
+	for range 2 {
+		_, err := s.CreateEntry(ctx, "Go", time.Now(), 0, "card")
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.MarkHandled(ctx, "mail", 1))
+	require.NoError(t, s.MarkHandled(ctx, "mail", 2))
+	require.NoError(t, s.MarkHandled(ctx, "mail", 3))
+
+	var saved bytes.Buffer
+	require.NoError(t, s.Save(&saved))
+	restored := NewStorage()
+	require.NoError(t, restored.Load(&saved))
+	require.NoError(t, s.DeleteMessages(ctx, 1))
+
+	testCases := []struct {
+		title     string
+		storage   *Storage
+		name      string
+		messageID int
+		want      bool
+	}{
+		{title: "happy: marked", storage: s, name: "mail", messageID: 2, want: true},
+		{title: "happy: marked before a restart", storage: restored, name: "mail", messageID: 1, want: true},
+		{title: "sad: marked by another handler", storage: s, name: "webhooks", messageID: 2},
+		{title: "sad: deleted with the message", storage: s, name: "mail", messageID: 1},
+		{title: "sad: no such message", storage: s, name: "mail", messageID: 3},
+	}
+
+	for _, tc := range testCases {
+		handled, err := tc.storage.Handled(ctx, tc.name, tc.messageID)
+		require.NoError(t, err, tc.title)
+		assert.Equal(t, tc.want, handled, tc.title)
+	}
+}
+
+func TestListWithoutOutbox(t *testing.T) {
+	l := NewEntryList()
+
+	_, err := l.CreateEntry(t.Context(), "Go", time.Now(), 0, "card")
+	assert.NoError(t, err, "a list on its own records no events")
+}
@@ -0,0 +1,61 @@
+package inmem
+
+import (
+	"context"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/models/reminder"
+	"sync"
+	"time"
+)
+
+type reminderKey struct {
+	entryID int
+	before  time.Duration
+}
+
+// ReminderList keeps which reminders were sent.
+// Concurrent-Use
+type ReminderList struct {
+	// sent has the course start of every sent reminder, for pruning
+	sent map[reminderKey]time.Time
+	// outbox gets the reminders to send, nil outside of Storage
+	outbox *Outbox
+	mtx    *sync.Mutex
+}
+
+func NewReminderList() ReminderList {
+	return ReminderList{
+		sent: make(map[reminderKey]time.Time),
+		mtx:  new(sync.Mutex),
+	}
+}
+
+func (rl *ReminderList) RecordReminder(ctx context.Context, r reminder.Reminder) (bool, error) {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	key := reminderKey{entryID: r.Entry.ID, before: r.Before}
+	if _, ok := rl.sent[key]; ok {
+		return false, nil
+	}
+
+	rl.sent[key] = r.StartsAt
+	rl.outbox.add(outbox.Message{Type: outbox.EntryReminder, UserID: r.Entry.UserID, Reminder: &r})
+
+	return true, nil
+}
+
+func (rl *ReminderList) PruneReminders(ctx context.Context, before time.Time) (int, error) {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	var pruned int
+	for key, startsAt := range rl.sent {
+		if startsAt.Before(before) {
+			delete(rl.sent, key)
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
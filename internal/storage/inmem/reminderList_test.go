@@ -0,0 +1,89 @@
+package inmem
+
+import (
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/models/reminder"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReminder(t *testing.T) {
+	s := NewStorage()
+	startsAt := time.Date(2025, 10, 5, 9, 0, 0, 0, time.UTC)
+	en := entry.Entry{ID: 3, Course: "go", UserID: 1, Status: "processed"}
+
+	testCases := []struct {
+		title    string
+		reminder reminder.Reminder
+		want     bool
+	}{
+		{title: "happy: first reminder", reminder: reminder.Reminder{Entry: en, Before: 72 * time.Hour, StartsAt: startsAt}, want: true},
+		{title: "happy: other offset", reminder: reminder.Reminder{Entry: en, Before: 2 * time.Hour, StartsAt: startsAt}, want: true},
+		{title: "sad: recorded already", reminder: reminder.Reminder{Entry: en, Before: 72 * time.Hour, StartsAt: startsAt}, want: false},
+	}
+
+	for _, tc := range testCases {
+		ok, err := s.RecordReminder(t.Context(), tc.reminder)
+		assert.Nil(t, err, tc.title)
+		assert.Equal(t, tc.want, ok, tc.title)
+	}
+
+	messages, err := s.PendingMessages(t.Context(), 0, 10)
+	require.Nil(t, err)
+	require.Len(t, messages, 2, "a reminder recorded again is not sent again")
+	assert.Equal(t, outbox.EntryReminder, messages[0].Type)
+	assert.Equal(t, 1, messages[0].UserID)
+	assert.Equal(t, 72*time.Hour, messages[0].Reminder.Before)
+	assert.Equal(t, 2*time.Hour, messages[1].Reminder.Before)
+}
+
+func TestPruneReminders(t *testing.T) {
+	l := NewReminderList()
+	now := time.Date(2025, 10, 5, 12, 0, 0, 0, time.UTC)
+
+	for i, startsAt := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		_, err := l.RecordReminder(t.Context(), reminder.Reminder{Entry: entry.Entry{ID: i}, Before: time.Hour, StartsAt: startsAt})
+		require.Nil(t, err)
+	}
+
+	pruned, err := l.PruneReminders(t.Context(), now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pruned)
+
+	ok, _ := l.RecordReminder(t.Context(), reminder.Reminder{Entry: entry.Entry{ID: 1}, Before: time.Hour})
+	assert.False(t, ok, "reminders of courses not started are kept")
+}
+
+func TestAcquireLease(t *testing.T) {
+	l := NewLeases()
+
+	testCases := []struct {
+		title  string
+		holder string
+		ttl    time.Duration
+		want   bool
+	}{
+		{title: "happy: free", holder: "one", ttl: time.Hour, want: true},
+		{title: "happy: renewed by the holder", holder: "one", ttl: -time.Second, want: true},
+		{title: "happy: expired", holder: "two", ttl: time.Hour, want: true},
+		{title: "sad: held by another", holder: "one", ttl: time.Hour, want: false},
+	}
+
+	for _, tc := range testCases {
+		ok, err := l.AcquireLease(t.Context(), "reminders", tc.holder, tc.ttl)
+		assert.Nil(t, err, tc.title)
+		assert.Equal(t, tc.want, ok, tc.title)
+	}
+
+	assert.Nil(t, l.ReleaseLease(t.Context(), "reminders", "one"))
+	ok, _ := l.AcquireLease(t.Context(), "reminders", "one", time.Hour)
+	assert.False(t, ok, "only the holder releases the lease")
+
+	assert.Nil(t, l.ReleaseLease(t.Context(), "reminders", "two"))
+	ok, _ = l.AcquireLease(t.Context(), "reminders", "one", time.Hour)
+	assert.True(t, ok, "a released lease is free")
+}
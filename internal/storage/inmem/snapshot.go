@@ -0,0 +1,337 @@
+package inmem
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/models/reminder"
+	"practice-backend/internal/models/user"
+	"practice-backend/internal/models/webhook"
+	"slices"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Save.
+const SnapshotVersion = 1
+
+var (
+	ErrUnsupportedSnapshot = errors.New("unsupported snapshot version")
+)
+
+type snapshot struct {
+	Version     int           `json:"version"`
+	NextUserID  int           `json:"next_user_id"`
+	Users       []userRecord  `json:"users"`
+	NextEntryID int           `json:"next_entry_id"`
+	Entries     []entryRecord `json:"entries"`
+
+	NextWebhookID  int              `json:"next_webhook_id"`
+	Webhooks       []webhookRecord  `json:"webhooks"`
+	NextDeliveryID int              `json:"next_delivery_id"`
+	Deliveries     []deliveryRecord `json:"deliveries"`
+
+	NextMessageID int             `json:"next_message_id"`
+	Outbox        []messageRecord `json:"outbox"`
+
+	Reminders []sentReminderRecord `json:"reminders"`
+
+	SavedAt time.Time `json:"saved_at"`
+}
+
+type userRecord struct {
+	ID          int    `json:"id"`
+	Login       string `json:"login"`
+	Password    string `json:"password"`
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	IsAdmin     bool   `json:"is_admin"`
+	Deactivated bool   `json:"deactivated"`
+	Language    string `json:"language,omitempty"`
+
+	EmailOptOuts  []string `json:"email_opt_outs,omitempty"`
+	CalendarToken string   `json:"calendar_token,omitempty"`
+}
+
+type entryRecord struct {
+	ID            int       `json:"id"`
+	Course        string    `json:"course"`
+	Date          time.Time `json:"date"`
+	UserID        int       `json:"user_id"`
+	PaymentMethod string    `json:"payment_method"`
+	Status        string    `json:"status"`
+	Version       int       `json:"version"`
+}
+
+type webhookRecord struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type deliveryRecord struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  time.Time       `json:"last_attempt_at"`
+	ResponseStatus int             `json:"response_status"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type messageRecord struct {
+	ID     int          `json:"id"`
+	Type   string       `json:"type"`
+	Time   time.Time    `json:"time"`
+	UserID int          `json:"user_id"`
+	Entry  *entryRecord `json:"entry,omitempty"`
+	User   *userRecord  `json:"user,omitempty"`
+
+	Reminder *reminderRecord `json:"reminder,omitempty"`
+	// Handled has the names of the handlers that marked the message
+	Handled []string `json:"handled,omitempty"`
+}
+
+type reminderRecord struct {
+	Entry         entryRecord `json:"entry"`
+	BeforeSeconds int64       `json:"before_seconds"`
+	StartsAt      time.Time   `json:"starts_at"`
+}
+
+type sentReminderRecord struct {
+	EntryID       int       `json:"entry_id"`
+	BeforeSeconds int64     `json:"before_seconds"`
+	StartsAt      time.Time `json:"starts_at"`
+}
+
+// Save writes the whole storage as JSON. All lists are locked at once, so
+// the outbox has the events of exactly the saved changes.
+func (s *Storage) Save(w io.Writer) error {
+	snap := snapshot{
+		Version: SnapshotVersion,
+		SavedAt: time.Now().UTC(),
+	}
+
+	s.UserList.mtx.Lock()
+	defer s.UserList.mtx.Unlock()
+	s.EntryList.mtx.Lock()
+	defer s.EntryList.mtx.Unlock()
+	s.WebhookList.mtx.Lock()
+	defer s.WebhookList.mtx.Unlock()
+	s.ReminderList.mtx.Lock()
+	defer s.ReminderList.mtx.Unlock()
+	s.Outbox.mtx.Lock()
+	defer s.Outbox.mtx.Unlock()
+
+	snap.NextUserID = s.UserList.list.GetLen()
+	for _, u := range s.UserList.list.GetData() {
+		snap.Users = append(snap.Users, userRecord(u))
+	}
+
+	snap.NextEntryID = s.EntryList.list.GetLen()
+	for _, e := range s.EntryList.list.GetData() {
+		snap.Entries = append(snap.Entries, entryRecord(e))
+	}
+
+	snap.NextWebhookID = s.WebhookList.list.GetLen()
+	for _, wh := range s.WebhookList.list.GetData() {
+		snap.Webhooks = append(snap.Webhooks, webhookRecord(wh))
+	}
+	snap.NextDeliveryID = s.WebhookList.deliveries.GetLen()
+	for _, d := range s.WebhookList.deliveries.GetData() {
+		snap.Deliveries = append(snap.Deliveries, deliveryRecord(d))
+	}
+
+	for key, startsAt := range s.ReminderList.sent {
+		snap.Reminders = append(snap.Reminders, sentReminderRecord{
+			EntryID:       key.entryID,
+			BeforeSeconds: int64(key.before / time.Second),
+			StartsAt:      startsAt,
+		})
+	}
+	slices.SortFunc(snap.Reminders, func(a, b sentReminderRecord) int {
+		return cmp.Or(cmp.Compare(a.EntryID, b.EntryID), cmp.Compare(a.BeforeSeconds, b.BeforeSeconds))
+	})
+
+	snap.NextMessageID = s.Outbox.nextID
+	for _, m := range s.Outbox.pending {
+		rec := messageRecord{
+			ID:     m.ID,
+			Type:   m.Type,
+			Time:   m.Time,
+			UserID: m.UserID,
+			Entry:  (*entryRecord)(m.Entry),
+			User:   (*userRecord)(m.User),
+
+			Handled: s.Outbox.handled[m.ID],
+		}
+		if r := m.Reminder; r != nil {
+			rec.Reminder = &reminderRecord{
+				Entry:         entryRecord(r.Entry),
+				BeforeSeconds: int64(r.Before / time.Second),
+				StartsAt:      r.StartsAt,
+			}
+		}
+		snap.Outbox = append(snap.Outbox, rec)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(snap)
+}
+
+// Load replaces the storage content with a snapshot written by Save.
+func (s *Storage) Load(r io.Reader) error {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+	if snap.Version > SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSnapshot, snap.Version)
+	}
+
+	users := NewUserList()
+	for _, rec := range snap.Users {
+		if err := users.list.Restore(rec.ID, user.User(rec)); err != nil {
+			return err
+		}
+		users.loginToUser[rec.Login] = rec.ID
+		if rec.CalendarToken != "" {
+			users.tokenToUser[rec.CalendarToken] = rec.ID
+		}
+	}
+	users.list.Reserve(snap.NextUserID)
+
+	entries := NewEntryList()
+	for _, rec := range snap.Entries {
+		// snapshots from before versions were added
+		if rec.Version == 0 {
+			rec.Version = 1
+		}
+		if err := entries.list.Restore(rec.ID, entry.Entry(rec)); err != nil {
+			return err
+		}
+	}
+	entries.list.Reserve(snap.NextEntryID)
+
+	webhooks := NewWebhookList()
+	for _, rec := range snap.Webhooks {
+		if err := webhooks.list.Restore(rec.ID, webhook.Webhook(rec)); err != nil {
+			return err
+		}
+	}
+	webhooks.list.Reserve(snap.NextWebhookID)
+	for _, rec := range snap.Deliveries {
+		if err := webhooks.deliveries.Restore(rec.ID, webhook.Delivery(rec)); err != nil {
+			return err
+		}
+	}
+	webhooks.deliveries.Reserve(snap.NextDeliveryID)
+
+	reminders := NewReminderList()
+	for _, rec := range snap.Reminders {
+		key := reminderKey{entryID: rec.EntryID, before: time.Duration(rec.BeforeSeconds) * time.Second}
+		reminders.sent[key] = rec.StartsAt
+	}
+
+	ob := NewOutbox()
+	for _, rec := range snap.Outbox {
+		m := outbox.Message{
+			ID:     rec.ID,
+			Type:   rec.Type,
+			Time:   rec.Time,
+			UserID: rec.UserID,
+			Entry:  (*entry.Entry)(rec.Entry),
+			User:   (*user.User)(rec.User),
+		}
+		if r := rec.Reminder; r != nil {
+			m.Reminder = &reminder.Reminder{
+				Entry:    entry.Entry(r.Entry),
+				Before:   time.Duration(r.BeforeSeconds) * time.Second,
+				StartsAt: r.StartsAt,
+			}
+		}
+		ob.pending = append(ob.pending, m)
+		if len(rec.Handled) > 0 {
+			ob.handled[m.ID] = rec.Handled
+		}
+	}
+	// snapshots from before the outbox have no next ID
+	ob.nextID = max(ob.nextID, snap.NextMessageID)
+
+	s.UserList.mtx.Lock()
+	s.UserList.list, s.UserList.loginToUser, s.UserList.tokenToUser = users.list, users.loginToUser, users.tokenToUser
+	s.UserList.mtx.Unlock()
+
+	s.EntryList.mtx.Lock()
+	s.EntryList.list = entries.list
+	s.EntryList.mtx.Unlock()
+
+	s.WebhookList.mtx.Lock()
+	s.WebhookList.list, s.WebhookList.deliveries = webhooks.list, webhooks.deliveries
+	s.WebhookList.mtx.Unlock()
+
+	s.ReminderList.mtx.Lock()
+	s.ReminderList.sent = reminders.sent
+	s.ReminderList.mtx.Unlock()
+
+	s.Outbox.mtx.Lock()
+	s.Outbox.pending, s.Outbox.handled, s.Outbox.nextID = ob.pending, ob.handled, ob.nextID
+	s.Outbox.mtx.Unlock()
+
+	return nil
+}
+
+// SaveFile atomically writes the snapshot to path.
+func (s *Storage) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile loads the snapshot from path. A missing file leaves
+// the storage empty.
+func (s *Storage) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	return s.Load(f)
+}
@@ -0,0 +1,119 @@
+package inmem
+
+import (
+	"bytes"
+	"path/filepath"
+	"practice-backend/internal/models/outbox"
+	"practice-backend/internal/models/reminder"
+	"practice-backend/internal/models/webhook"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s := NewStorage()
+
+	one, err := s.CreateUser(t.Context(), "one", "hash", "Ivan", "Ivanov", "Ivanovich", "89991234567", "ivan@example.com", true)
+	require.Nil(t, err)
+	_, err = s.SetCalendarToken(t.Context(), one.ID, "token")
+	require.Nil(t, err)
+	_, err = s.CreateUser(t.Context(), "two", "hash", "", "", "", "", "", false)
+	require.Nil(t, err)
+	require.Nil(t, s.DeleteUser(t.Context(), 1))
+
+	date := time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC)
+	created, err := s.CreateEntry(t.Context(), "go", date, 0, "card")
+	require.Nil(t, err)
+
+	hook, err := s.CreateWebhook(t.Context(), "http://crm", []string{"entry.created"}, "secret")
+	require.Nil(t, err)
+	_, err = s.CreateDelivery(t.Context(), *webhook.NewDelivery(hook.ID, "entry.created", []byte(`{"type":"entry.created"}`)))
+	require.Nil(t, err)
+
+	startsAt := date.Add(9 * time.Hour)
+	_, err = s.RecordReminder(t.Context(), reminder.Reminder{Entry: created, Before: 2 * time.Hour, StartsAt: startsAt})
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, s.Save(&buf))
+
+	loaded := NewStorage()
+	require.Nil(t, loaded.Load(&buf))
+
+	u, err := loaded.GetUserByLogin(t.Context(), "one")
+	assert.Nil(t, err)
+	assert.Equal(t, "Ivanovich", u.Patronymic)
+	assert.True(t, u.IsAdmin)
+
+	u, err = loaded.GetUserByCalendarToken(t.Context(), "token")
+	assert.Nil(t, err)
+	assert.Equal(t, "one", u.Login)
+
+	_, err = loaded.GetUserByLogin(t.Context(), "two")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	e, err := loaded.GetEntryByID(t.Context(), 0)
+	assert.Nil(t, err)
+	assert.True(t, date.Equal(e.Date))
+
+	loadedHook, err := loaded.GetWebhookByID(t.Context(), hook.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", loadedHook.Secret)
+
+	d, err := loaded.GetDeliveryByID(t.Context(), 0)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"type":"entry.created"}`, string(d.Payload))
+	assert.Equal(t, webhook.StatusPending, d.Status)
+
+	messages, err := loaded.PendingMessages(t.Context(), 0, 10)
+	assert.Nil(t, err)
+	require.Len(t, messages, 6)
+	assert.Equal(t, outbox.UserUpdated, messages[1].Type)
+	assert.Empty(t, messages[1].User.CalendarToken, "events don't carry the calendar secret")
+	assert.Equal(t, outbox.UserDeleted, messages[3].Type)
+	assert.Equal(t, "two", messages[3].User.Login)
+	assert.Equal(t, outbox.EntryCreated, messages[4].Type)
+	assert.True(t, date.Equal(messages[4].Entry.Date))
+
+	assert.Equal(t, outbox.EntryReminder, messages[5].Type)
+	assert.Equal(t, 2*time.Hour, messages[5].Reminder.Before)
+	assert.True(t, startsAt.Equal(messages[5].Reminder.StartsAt))
+	assert.Equal(t, "go", messages[5].Reminder.Entry.Course)
+
+	ok, err := loaded.RecordReminder(t.Context(), reminder.Reminder{Entry: created, Before: 2 * time.Hour, StartsAt: startsAt})
+	assert.Nil(t, err)
+	assert.False(t, ok, "sent reminders are not sent again")
+
+	// deleted IDs are not handed out again
+	createdUser, err := loaded.CreateUser(t.Context(), "three", "", "", "", "", "", "", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, createdUser.ID)
+
+	messages, err = loaded.PendingMessages(t.Context(), 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, messages[6].ID, "message IDs are not handed out again")
+}
+
+func TestSnapshotUnsupportedVersion(t *testing.T) {
+	err := NewStorage().Load(strings.NewReader(`{"version": 100}`))
+	assert.ErrorIs(t, err, ErrUnsupportedSnapshot)
+}
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	s := NewStorage()
+	assert.Nil(t, s.LoadFile(path), "missing file is an empty storage")
+
+	s.CreateUser(t.Context(), "one", "", "", "", "", "", "", false)
+	require.Nil(t, s.SaveFile(path))
+
+	loaded := NewStorage()
+	require.Nil(t, loaded.LoadFile(path))
+	_, err := loaded.GetUserByLogin(t.Context(), "one")
+	assert.Nil(t, err)
+}
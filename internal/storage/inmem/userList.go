@@ -3,6 +3,7 @@ package inmem
 import (
 	"context"
 	"errors"
+	"practice-backend/internal/models/outbox"
 	"practice-backend/internal/models/user"
 	"practice-backend/internal/storage/inmem/ilist"
 	"sync"
@@ -11,19 +12,24 @@ import (
 var (
 	ErrUserNotFound     = errors.New("user not found")
 	ErrUserAlreadyExist = errors.New("user already exist")
+	ErrCalendarNotFound = errors.New("calendar not found")
 )
 
 // Concurrent-Use
 type UserList struct {
 	list        ilist.List[user.User]
-	loginToUser map[string]*user.User
-	mtx         *sync.Mutex
+	loginToUser map[string]int
+	tokenToUser map[string]int
+	// outbox gets the events of the changes, nil outside of Storage
+	outbox *Outbox
+	mtx    *sync.Mutex
 }
 
 func NewUserList() UserList {
 	return UserList{
 		list:        ilist.NewList[user.User](),
-		loginToUser: make(map[string]*user.User),
+		loginToUser: make(map[string]int),
+		tokenToUser: make(map[string]int),
 		mtx:         new(sync.Mutex),
 	}
 }
@@ -47,11 +53,39 @@ func (ul *UserList) GetUserByLogin(ctx context.Context, login string) (user.User
 	ul.mtx.Lock()
 	defer ul.mtx.Unlock()
 
-	usr, ok := ul.loginToUser[login]
+	id, ok := ul.loginToUser[login]
 	if !ok {
 		return user.User{}, ErrUserNotFound
 	}
-	return *usr, nil
+
+	u, err := ul.list.GetDataByID(id)
+	if err != nil {
+		return user.User{}, ErrUserNotFound
+	}
+	return *u, nil
+}
+
+func (ul *UserList) GetUserByCalendarToken(ctx context.Context, token string) (user.User, error) {
+	ul.mtx.Lock()
+	defer ul.mtx.Unlock()
+
+	id, ok := ul.tokenToUser[token]
+	if !ok {
+		return user.User{}, ErrCalendarNotFound
+	}
+
+	u, err := ul.list.GetDataByID(id)
+	if err != nil {
+		return user.User{}, ErrCalendarNotFound
+	}
+	return *u, nil
+}
+
+func (ul *UserList) GetUsers(ctx context.Context) ([]user.User, error) {
+	ul.mtx.Lock()
+	defer ul.mtx.Unlock()
+
+	return ul.list.GetData(), nil
 }
 
 func (el *UserList) CreateUser(
@@ -76,34 +110,143 @@ func (el *UserList) CreateUser(
 		isAdmin,
 	)
 
-	if _, err := el.GetUserByLogin(ctx, login); err == nil {
-		return user.User{}, ErrUserAlreadyExist
-	}
-
 	el.mtx.Lock()
 	defer el.mtx.Unlock()
 
+	if _, ok := el.loginToUser[login]; ok {
+		return user.User{}, ErrUserAlreadyExist
+	}
+
 	newUser.ID = el.list.GetLen()
 
 	e, err := el.list.AddData(*newUser)
 	if err != nil {
 		return user.User{}, err
 	}
-	el.loginToUser[newUser.Login] = newUser
+	el.loginToUser[newUser.Login] = newUser.ID
+	el.addEvent(outbox.UserRegistered, e)
 
 	return e, nil
 }
 
+func (ul *UserList) UpdateUser(ctx context.Context, updated user.User) (user.User, error) {
+	ul.mtx.Lock()
+	defer ul.mtx.Unlock()
+
+	u, err := ul.list.GetDataByID(updated.ID)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return user.User{}, ErrUserNotFound
+		}
+		return user.User{}, err
+	}
+
+	if updated.Login != u.Login {
+		if _, ok := ul.loginToUser[updated.Login]; ok {
+			return user.User{}, ErrUserAlreadyExist
+		}
+		delete(ul.loginToUser, u.Login)
+		ul.loginToUser[updated.Login] = updated.ID
+	}
+	updated.Password = u.Password
+	updated.IsAdmin = u.IsAdmin
+	updated.Deactivated = u.Deactivated
+	updated.CalendarToken = u.CalendarToken
+
+	updated, err = ul.list.UpdateData(updated.ID, updated)
+	if err != nil {
+		return user.User{}, err
+	}
+	ul.addEvent(outbox.UserUpdated, updated)
+
+	return updated, nil
+}
+
+func (ul *UserList) SetAdmin(ctx context.Context, id int, isAdmin bool) (user.User, error) {
+	return ul.update(id, func(u *user.User) { u.IsAdmin = isAdmin })
+}
+
+func (ul *UserList) SetDeactivated(ctx context.Context, id int, deactivated bool) (user.User, error) {
+	return ul.update(id, func(u *user.User) { u.Deactivated = deactivated })
+}
+
+func (ul *UserList) SetCalendarToken(ctx context.Context, id int, token string) (user.User, error) {
+	return ul.update(id, func(u *user.User) {
+		delete(ul.tokenToUser, u.CalendarToken)
+		if token != "" {
+			ul.tokenToUser[token] = u.ID
+		}
+		u.CalendarToken = token
+	})
+}
+
+// update changes the stored user in place under ul.mtx and records the
+// change.
+func (ul *UserList) update(id int, change func(u *user.User)) (user.User, error) {
+	ul.mtx.Lock()
+	defer ul.mtx.Unlock()
+
+	u, err := ul.list.GetDataByID(id)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return user.User{}, ErrUserNotFound
+		}
+		return user.User{}, err
+	}
+
+	change(u)
+	ul.addEvent(outbox.UserUpdated, *u)
+
+	return *u, nil
+}
+
+func (ul *UserList) ChangePassword(ctx context.Context, id int, password string) error {
+	ul.mtx.Lock()
+	defer ul.mtx.Unlock()
+
+	u, err := ul.list.GetDataByID(id)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	u.Password = password
+
+	return nil
+}
+
 func (ul *UserList) DeleteUser(ctx context.Context, id int) error {
 	ul.mtx.Lock()
 	defer ul.mtx.Unlock()
 
-	if err := ul.list.DeleteData(id); err != nil {
+	u, err := ul.list.GetDataByID(id)
+	if err != nil {
 		if errors.Is(err, ilist.ErrDataNotFound) {
 			return ErrUserNotFound
 		}
 		return err
 	}
+	deleted := *u
+
+	if err := ul.list.DeleteData(id); err != nil {
+		return err
+	}
+	delete(ul.loginToUser, deleted.Login)
+	delete(ul.tokenToUser, deleted.CalendarToken)
+	ul.addEvent(outbox.UserDeleted, deleted)
 
 	return nil
 }
+
+// addEvent records the event of the change of u. The caller must hold
+// ul.mtx.
+func (ul *UserList) addEvent(eventType string, u user.User) {
+	// the password hash and the calendar secret must not reach
+	// subscribers
+	u.Password = ""
+	u.CalendarToken = ""
+
+	ul.outbox.add(outbox.Message{Type: eventType, UserID: u.ID, User: &u})
+}
@@ -186,3 +186,193 @@ func TestDeleteUser(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateUser(t *testing.T) {
+	l := NewUserList()
+
+	first, _ := l.CreateUser(t.Context(), "first", "hash", "Ivan", "", "", "", "", false)
+	l.CreateUser(t.Context(), "second", "hash", "Petr", "", "", "", "", false)
+
+	testCases := []struct {
+		title   string
+		update  func(u user.User) user.User
+		wantErr string
+	}{
+		{
+			title: "happy: update profile fields",
+			update: func(u user.User) user.User {
+				u.Name = "Sergey"
+				u.Email = "sergey@example.com"
+				return u
+			},
+			wantErr: "",
+		},
+		{
+			title: "happy: password is not overwritten",
+			update: func(u user.User) user.User {
+				u.Password = "other"
+				return u
+			},
+			wantErr: "",
+		},
+		{
+			title: "happy: role and deactivation are not overwritten",
+			update: func(u user.User) user.User {
+				u.IsAdmin = true
+				u.Deactivated = true
+				return u
+			},
+			wantErr: "",
+		},
+		{
+			title: "sad: login taken by another user",
+			update: func(u user.User) user.User {
+				u.Login = "second"
+				return u
+			},
+			wantErr: "user already exist",
+		},
+		{
+			title: "sad: update not existing user",
+			update: func(u user.User) user.User {
+				u.ID = 10
+				return u
+			},
+			wantErr: "user not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		current, _ := l.GetUserByID(t.Context(), first.ID)
+
+		updated, err := l.UpdateUser(t.Context(), tc.update(current))
+		if tc.wantErr != "" {
+			assert.Contains(t, err.Error(), tc.wantErr, tc.title)
+			continue
+		}
+		assert.Nil(t, err, tc.title)
+		assert.Equal(t, "hash", updated.Password, tc.title)
+		assert.False(t, updated.IsAdmin, tc.title)
+		assert.False(t, updated.Deactivated, tc.title)
+
+		stored, err := l.GetUserByLogin(t.Context(), "first")
+		assert.Nil(t, err, tc.title)
+		assert.Equal(t, updated, stored, tc.title)
+	}
+}
+
+func TestSetAdminAndDeactivated(t *testing.T) {
+	l := NewUserList()
+	ctx := context.Background()
+
+	u, _ := l.CreateUser(ctx, "ivan", "hash", "Ivan", "", "", "", "", false)
+
+	// a profile update read before the admin actions and written after them
+	stale := u
+	stale.Name = "Sergey"
+
+	updated, err := l.SetAdmin(ctx, u.ID, true)
+	assert.Nil(t, err)
+	assert.True(t, updated.IsAdmin)
+	updated, err = l.SetDeactivated(ctx, u.ID, true)
+	assert.Nil(t, err)
+	assert.True(t, updated.Deactivated)
+
+	updated, err = l.UpdateUser(ctx, stale)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sergey", updated.Name)
+	assert.True(t, updated.IsAdmin, "a stale profile update doesn't revoke the role")
+	assert.True(t, updated.Deactivated, "a stale profile update doesn't reactivate")
+
+	_, err = l.SetAdmin(ctx, 10, true)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = l.SetDeactivated(ctx, 10, true)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestChangePassword(t *testing.T) {
+	l := NewUserList()
+
+	u, _ := l.CreateUser(t.Context(), "login123", "old", "", "", "", "", "", false)
+
+	assert.Nil(t, l.ChangePassword(t.Context(), u.ID, "new"))
+
+	stored, err := l.GetUserByLogin(t.Context(), "login123")
+	assert.Nil(t, err)
+	assert.Equal(t, "new", stored.Password)
+
+	assert.ErrorIs(t, l.ChangePassword(t.Context(), 10, "new"), ErrUserNotFound)
+}
+
+func TestDeleteUserFreesLogin(t *testing.T) {
+	l := NewUserList()
+
+	u, _ := l.CreateUser(t.Context(), "login123", "", "", "", "", "", "", false)
+	assert.Nil(t, l.DeleteUser(t.Context(), u.ID))
+
+	_, err := l.GetUserByLogin(t.Context(), "login123")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = l.CreateUser(t.Context(), "login123", "", "", "", "", "", "", false)
+	assert.Nil(t, err)
+}
+
+func TestGetUsers(t *testing.T) {
+	l := NewUserList()
+
+	for _, login := range []string{"one", "two", "three"} {
+		l.CreateUser(t.Context(), login, "", "", "", "", "", "", false)
+	}
+	assert.Nil(t, l.DeleteUser(t.Context(), 1))
+
+	users, err := l.GetUsers(t.Context())
+	assert.Nil(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "one", users[0].Login)
+		assert.Equal(t, "three", users[1].Login)
+	}
+}
+
+func TestGetUserByCalendarToken(t *testing.T) {
+	l := NewUserList()
+	ctx := context.Background()
+
+	u, _ := l.CreateUser(ctx, "ivan", "pass123", "Ivan", "", "", "", "", false)
+	l.SetCalendarToken(ctx, u.ID, "old")
+	l.SetCalendarToken(ctx, u.ID, "new")
+
+	// a profile update read before the token was set and written after
+	u.Name = "Sergey"
+	l.UpdateUser(ctx, u)
+
+	deleted, _ := l.CreateUser(ctx, "petr", "pass123", "Petr", "", "", "", "", false)
+	l.SetCalendarToken(ctx, deleted.ID, "deleted")
+	l.DeleteUser(ctx, deleted.ID)
+
+	off, _ := l.CreateUser(ctx, "olga", "pass123", "Olga", "", "", "", "", false)
+	l.SetCalendarToken(ctx, off.ID, "off")
+	l.SetCalendarToken(ctx, off.ID, "")
+
+	testCases := []struct {
+		title   string
+		token   string
+		wantID  int
+		wantErr error
+	}{
+		{title: "happy: current token, kept by a stale profile update", token: "new", wantID: u.ID},
+		{title: "sad: rotated token", token: "old", wantErr: ErrCalendarNotFound},
+		{title: "sad: token of a deleted user", token: "deleted", wantErr: ErrCalendarNotFound},
+		{title: "sad: feed turned off", token: "off", wantErr: ErrCalendarNotFound},
+		{title: "sad: empty token", token: "", wantErr: ErrCalendarNotFound},
+	}
+
+	for _, tc := range testCases {
+		got, err := l.GetUserByCalendarToken(ctx, tc.token)
+		if tc.wantErr != nil {
+			assert.ErrorIs(t, err, tc.wantErr, tc.title)
+			continue
+		}
+		assert.Nil(t, err, tc.title)
+		assert.Equal(t, tc.wantID, got.ID, tc.title)
+	}
+}
@@ -0,0 +1,177 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"practice-backend/internal/models/webhook"
+	"practice-backend/internal/storage/inmem/ilist"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// Concurrent-Use
+type WebhookList struct {
+	list       ilist.List[webhook.Webhook]
+	deliveries ilist.List[webhook.Delivery]
+	mtx        *sync.Mutex
+}
+
+func NewWebhookList() WebhookList {
+	return WebhookList{
+		list:       ilist.NewList[webhook.Webhook](),
+		deliveries: ilist.NewList[webhook.Delivery](),
+		mtx:        new(sync.Mutex),
+	}
+}
+
+func (wl *WebhookList) CreateWebhook(ctx context.Context, url string, events []string, secret string) (webhook.Webhook, error) {
+	newWebhook := webhook.NewWebhook(url, slices.Clone(events), secret)
+
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	newWebhook.ID = wl.list.GetLen()
+
+	return wl.list.AddData(*newWebhook)
+}
+
+func (wl *WebhookList) GetWebhookByID(ctx context.Context, id int) (webhook.Webhook, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	w, err := wl.list.GetDataByID(id)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return webhook.Webhook{}, ErrWebhookNotFound
+		}
+		return webhook.Webhook{}, err
+	}
+
+	return *w, nil
+}
+
+func (wl *WebhookList) GetWebhooks(ctx context.Context) ([]webhook.Webhook, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	return wl.list.GetData(), nil
+}
+
+func (wl *WebhookList) DeleteWebhook(ctx context.Context, id int) error {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	if err := wl.list.DeleteData(id); err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+
+	for _, d := range wl.deliveries.GetData() {
+		if d.WebhookID == id {
+			wl.deliveries.DeleteData(d.ID)
+		}
+	}
+
+	return nil
+}
+
+func (wl *WebhookList) CreateDelivery(ctx context.Context, d webhook.Delivery) (webhook.Delivery, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	if _, err := wl.list.GetDataByID(d.WebhookID); err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return webhook.Delivery{}, ErrWebhookNotFound
+		}
+		return webhook.Delivery{}, err
+	}
+
+	d.ID = wl.deliveries.GetLen()
+
+	return wl.deliveries.AddData(d)
+}
+
+func (wl *WebhookList) GetDeliveryByID(ctx context.Context, id int) (webhook.Delivery, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	d, err := wl.deliveries.GetDataByID(id)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return webhook.Delivery{}, ErrDeliveryNotFound
+		}
+		return webhook.Delivery{}, err
+	}
+
+	return *d, nil
+}
+
+func (wl *WebhookList) GetDeliveries(ctx context.Context, webhookID int) ([]webhook.Delivery, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	deliveries := wl.deliveries.GetData()
+	if webhookID < 0 {
+		return deliveries, nil
+	}
+
+	return slices.DeleteFunc(deliveries, func(d webhook.Delivery) bool {
+		return d.WebhookID != webhookID
+	}), nil
+}
+
+func (wl *WebhookList) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]webhook.Delivery, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	var due []webhook.Delivery
+	for _, d := range wl.deliveries.GetData() {
+		if d.Status == webhook.StatusPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b webhook.Delivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	return due[:min(len(due), limit)], nil
+}
+
+func (wl *WebhookList) UpdateDelivery(ctx context.Context, d webhook.Delivery) (webhook.Delivery, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	updated, err := wl.deliveries.UpdateData(d.ID, d)
+	if err != nil {
+		if errors.Is(err, ilist.ErrDataNotFound) {
+			return webhook.Delivery{}, ErrDeliveryNotFound
+		}
+		return webhook.Delivery{}, err
+	}
+
+	return updated, nil
+}
+
+func (wl *WebhookList) PruneDeliveries(ctx context.Context, before time.Time) (int, error) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+
+	pruned := 0
+	for _, d := range wl.deliveries.GetData() {
+		if d.Status == webhook.StatusDelivered && d.CreatedAt.Before(before) {
+			wl.deliveries.DeleteData(d.ID)
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
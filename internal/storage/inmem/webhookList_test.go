@@ -0,0 +1,102 @@
+package inmem
+
+import (
+	"context"
+	"practice-backend/internal/models/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDueDeliveries(t *testing.T) {
+	l := NewWebhookList()
+	ctx := context.Background()
+
+	hook, err := l.CreateWebhook(ctx, "http://crm", []string{"entry.created"}, "secret")
+	require.Nil(t, err)
+
+	now := time.Date(2025, 10, 5, 12, 0, 0, 0, time.UTC)
+	for _, d := range []webhook.Delivery{
+		{Status: webhook.StatusPending, NextAttemptAt: now.Add(-time.Minute)},
+		{Status: webhook.StatusPending, NextAttemptAt: now.Add(-time.Hour)},
+		{Status: webhook.StatusPending, NextAttemptAt: now.Add(time.Minute)},
+		{Status: webhook.StatusDead, NextAttemptAt: now.Add(-time.Hour)},
+		{Status: webhook.StatusDelivered, NextAttemptAt: now.Add(-time.Hour)},
+	} {
+		d.WebhookID = hook.ID
+		_, err := l.CreateDelivery(ctx, d)
+		require.Nil(t, err)
+	}
+
+	testCases := []struct {
+		title   string
+		limit   int
+		wantIDs []int
+	}{
+		{title: "happy: pending and due, longest waiting first", limit: 10, wantIDs: []int{1, 0}},
+		{title: "happy: limit", limit: 1, wantIDs: []int{1}},
+	}
+
+	for _, tc := range testCases {
+		due, err := l.DueDeliveries(ctx, now, tc.limit)
+		assert.Nil(t, err, tc.title)
+
+		var ids []int
+		for _, d := range due {
+			ids = append(ids, d.ID)
+		}
+		assert.Equal(t, tc.wantIDs, ids, tc.title)
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	l := NewWebhookList()
+	ctx := context.Background()
+
+	kept, _ := l.CreateWebhook(ctx, "http://crm", []string{"entry.created"}, "secret")
+	deleted, _ := l.CreateWebhook(ctx, "http://accounting", []string{"entry.created"}, "secret")
+	l.CreateDelivery(ctx, *webhook.NewDelivery(kept.ID, "entry.created", []byte("{}")))
+	l.CreateDelivery(ctx, *webhook.NewDelivery(deleted.ID, "entry.created", []byte("{}")))
+
+	require.Nil(t, l.DeleteWebhook(ctx, deleted.ID))
+	assert.ErrorIs(t, l.DeleteWebhook(ctx, deleted.ID), ErrWebhookNotFound)
+
+	all, err := l.GetDeliveries(ctx, -1)
+	assert.Nil(t, err)
+	require.Len(t, all, 1, "deliveries of the deleted webhook are deleted too")
+	assert.Equal(t, kept.ID, all[0].WebhookID)
+
+	_, err = l.CreateDelivery(ctx, *webhook.NewDelivery(deleted.ID, "entry.created", []byte("{}")))
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestPruneDeliveries(t *testing.T) {
+	l := NewWebhookList()
+	ctx := context.Background()
+
+	hook, _ := l.CreateWebhook(ctx, "http://crm", []string{"entry.created"}, "secret")
+
+	before := time.Now().UTC()
+	old := before.Add(-time.Hour)
+	for _, d := range []webhook.Delivery{
+		{Status: webhook.StatusDelivered, CreatedAt: old},
+		{Status: webhook.StatusDead, CreatedAt: old},
+		{Status: webhook.StatusPending, CreatedAt: old},
+		{Status: webhook.StatusDelivered, CreatedAt: before.Add(time.Hour)},
+	} {
+		d.WebhookID = hook.ID
+		l.CreateDelivery(ctx, d)
+	}
+
+	pruned, err := l.PruneDeliveries(ctx, before)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pruned, "only old delivered deliveries are pruned")
+
+	_, err = l.GetDeliveryByID(ctx, 0)
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	left, _ := l.GetDeliveries(ctx, hook.ID)
+	assert.Len(t, left, 3)
+}
@@ -0,0 +1,79 @@
+package instrumented
+
+import (
+	"context"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/models/entry"
+	"time"
+)
+
+// EntryRepo records metrics and a span for every call to the wrapped repo,
+// along with created entries and status transitions.
+type EntryRepo struct {
+	repo    entry.EntryRepo
+	metrics *metrics.Metrics
+}
+
+func NewEntryRepo(repo entry.EntryRepo, m *metrics.Metrics) *EntryRepo {
+	return &EntryRepo{
+		repo:    repo,
+		metrics: m,
+	}
+}
+
+// track starts a span and a timer for the operation; the returned func
+// ends them with the final value of *err.
+func (r *EntryRepo) track(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	return startOperation(ctx, r.metrics, "entry", operation)
+}
+
+func (r *EntryRepo) CreateEntry(
+	ctx context.Context,
+	course string,
+	date time.Time,
+	userID int,
+	paymentMethod string,
+) (e entry.Entry, err error) {
+	ctx, done := r.track(ctx, "create_entry")
+	defer done(&err)
+
+	e, err = r.repo.CreateEntry(ctx, course, date, userID, paymentMethod)
+	if err == nil {
+		r.metrics.IncEntryCreated(e.Status)
+	}
+
+	return e, err
+}
+
+func (r *EntryRepo) GetEntryByID(ctx context.Context, id int) (e entry.Entry, err error) {
+	ctx, done := r.track(ctx, "get_entry_by_id")
+	defer done(&err)
+
+	return r.repo.GetEntryByID(ctx, id)
+}
+
+func (r *EntryRepo) GetEntries(ctx context.Context) (entries []entry.Entry, err error) {
+	ctx, done := r.track(ctx, "get_entries")
+	defer done(&err)
+
+	return r.repo.GetEntries(ctx)
+}
+
+func (r *EntryRepo) DeleteEntry(ctx context.Context, id int, version int) (err error) {
+	ctx, done := r.track(ctx, "delete_entry")
+	defer done(&err)
+
+	return r.repo.DeleteEntry(ctx, id, version)
+}
+
+func (r *EntryRepo) UpdateStatusEntry(ctx context.Context, id int, status string, version int) (e entry.Entry, previous string, err error) {
+	ctx, done := r.track(ctx, "update_status_entry")
+	defer done(&err)
+
+	e, previous, err = r.repo.UpdateStatusEntry(ctx, id, status, version)
+	if err == nil && previous != e.Status {
+		r.metrics.IncEntryTransition(previous, e.Status)
+	}
+
+	return e, previous, err
+}
@@ -0,0 +1,104 @@
+package instrumented
+
+import (
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/models/entry"
+	"practice-backend/internal/storage/inmem"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryRepoMetrics(t *testing.T) {
+	m := metrics.New()
+	storage := inmem.NewStorage()
+	repo := NewEntryRepo(storage, m)
+
+	e, err := repo.CreateEntry(t.Context(), "go", time.Now(), 0, "card")
+	require.Nil(t, err)
+
+	for _, status := range []string{"processed", "rejected", "rejected"} {
+		_, _, err = repo.UpdateStatusEntry(t.Context(), e.ID, status, entry.AnyVersion)
+		require.Nil(t, err)
+	}
+
+	_, err = repo.GetEntryByID(t.Context(), 10)
+	require.ErrorIs(t, err, inmem.ErrEntryNotFound)
+
+	expected := `
+# HELP practice_backend_entries_created_total Created entries by initial status.
+# TYPE practice_backend_entries_created_total counter
+practice_backend_entries_created_total{status="not processed"} 1
+# HELP practice_backend_entry_status_transitions_total Entry status changes by previous and new status.
+# TYPE practice_backend_entry_status_transitions_total counter
+practice_backend_entry_status_transitions_total{from="not processed",to="processed"} 1
+practice_backend_entry_status_transitions_total{from="processed",to="rejected"} 1
+`
+	err = testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected),
+		"practice_backend_entries_created_total",
+		"practice_backend_entry_status_transitions_total",
+	)
+	assert.Nil(t, err)
+
+	count, err := testutil.GatherAndCount(m.Registry(), "practice_backend_storage_operation_duration_seconds")
+	assert.Nil(t, err)
+	// create_entry/ok, update_status_entry/ok, get_entry_by_id/error
+	assert.Equal(t, 3, count)
+}
+
+func TestEntryRepoConcurrentTransitions(t *testing.T) {
+	m := metrics.New()
+	repo := NewEntryRepo(inmem.NewStorage(), m)
+
+	e, err := repo.CreateEntry(t.Context(), "go", time.Now(), 0, "card")
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		status := []string{"processed", "rejected"}[i%2]
+		wg.Go(func() {
+			_, _, err := repo.UpdateStatusEntry(t.Context(), e.ID, status, entry.AnyVersion)
+			assert.Nil(t, err)
+		})
+	}
+	wg.Wait()
+
+	families, err := m.Registry().Gather()
+	require.Nil(t, err)
+	counts := map[[2]string]float64{}
+	for _, family := range families {
+		if family.GetName() != "practice_backend_entry_status_transitions_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			counts[[2]string{labels["from"], labels["to"]}] = metric.GetCounter().GetValue()
+		}
+	}
+	transitions := func(from, to string) float64 {
+		return counts[[2]string{from, to}]
+	}
+	// the transitions recorded chain from the first status to the final
+	// one: the entry left every status as often as it got it, but the
+	// final one, which it got once more
+	final, err := repo.GetEntryByID(t.Context(), e.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1.0, transitions("not processed", "processed")+transitions("not processed", "rejected"))
+	for _, status := range []string{"processed", "rejected"} {
+		other := map[string]string{"processed": "rejected", "rejected": "processed"}[status]
+		got := transitions("not processed", status) + transitions(other, status)
+		left := transitions(status, other)
+		if status == final.Status {
+			got--
+		}
+		assert.Equal(t, left, got, status)
+	}
+}
@@ -0,0 +1,41 @@
+// Package instrumented wraps the repositories with metrics and tracing.
+package instrumented
+
+import (
+	"context"
+	"practice-backend/internal/metrics"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("practice-backend/internal/storage/instrumented")
+
+func startOperation(
+	ctx context.Context,
+	m *metrics.Metrics,
+	repo string,
+	operation string,
+) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, repo+"_repo."+operation,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("repo", repo),
+			attribute.String("operation", operation),
+		),
+	)
+
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+
+		m.ObserveStorageOperation(repo, operation, *err, time.Since(start))
+	}
+}
@@ -0,0 +1,113 @@
+package instrumented
+
+import (
+	"context"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/models/user"
+)
+
+// UserRepo records metrics and a span for every call to the wrapped repo.
+type UserRepo struct {
+	repo    user.UserRepo
+	metrics *metrics.Metrics
+}
+
+func NewUserRepo(repo user.UserRepo, m *metrics.Metrics) *UserRepo {
+	return &UserRepo{
+		repo:    repo,
+		metrics: m,
+	}
+}
+
+// track starts a span and a timer for the operation; the returned func
+// ends them with the final value of *err.
+func (r *UserRepo) track(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	return startOperation(ctx, r.metrics, "user", operation)
+}
+
+func (r *UserRepo) CreateUser(
+	ctx context.Context,
+	login string,
+	password string,
+	name string,
+	surname string,
+	patronymic string,
+	phone string,
+	email string,
+	isAdmin bool,
+) (u user.User, err error) {
+	ctx, done := r.track(ctx, "create_user")
+	defer done(&err)
+
+	return r.repo.CreateUser(ctx, login, password, name, surname, patronymic, phone, email, isAdmin)
+}
+
+func (r *UserRepo) GetUserByID(ctx context.Context, id int) (u user.User, err error) {
+	ctx, done := r.track(ctx, "get_user_by_id")
+	defer done(&err)
+
+	return r.repo.GetUserByID(ctx, id)
+}
+
+func (r *UserRepo) GetUserByLogin(ctx context.Context, login string) (u user.User, err error) {
+	ctx, done := r.track(ctx, "get_user_by_login")
+	defer done(&err)
+
+	return r.repo.GetUserByLogin(ctx, login)
+}
+
+func (r *UserRepo) GetUserByCalendarToken(ctx context.Context, token string) (u user.User, err error) {
+	ctx, done := r.track(ctx, "get_user_by_calendar_token")
+	defer done(&err)
+
+	return r.repo.GetUserByCalendarToken(ctx, token)
+}
+
+func (r *UserRepo) GetUsers(ctx context.Context) (users []user.User, err error) {
+	ctx, done := r.track(ctx, "get_users")
+	defer done(&err)
+
+	return r.repo.GetUsers(ctx)
+}
+
+func (r *UserRepo) UpdateUser(ctx context.Context, usr user.User) (u user.User, err error) {
+	ctx, done := r.track(ctx, "update_user")
+	defer done(&err)
+
+	return r.repo.UpdateUser(ctx, usr)
+}
+
+func (r *UserRepo) SetAdmin(ctx context.Context, id int, isAdmin bool) (u user.User, err error) {
+	ctx, done := r.track(ctx, "set_admin")
+	defer done(&err)
+
+	return r.repo.SetAdmin(ctx, id, isAdmin)
+}
+
+func (r *UserRepo) SetDeactivated(ctx context.Context, id int, deactivated bool) (u user.User, err error) {
+	ctx, done := r.track(ctx, "set_deactivated")
+	defer done(&err)
+
+	return r.repo.SetDeactivated(ctx, id, deactivated)
+}
+
+func (r *UserRepo) SetCalendarToken(ctx context.Context, id int, token string) (u user.User, err error) {
+	ctx, done := r.track(ctx, "set_calendar_token")
+	defer done(&err)
+
+	return r.repo.SetCalendarToken(ctx, id, token)
+}
+
+func (r *UserRepo) ChangePassword(ctx context.Context, id int, password string) (err error) {
+	ctx, done := r.track(ctx, "change_password")
+	defer done(&err)
+
+	return r.repo.ChangePassword(ctx, id, password)
+}
+
+func (r *UserRepo) DeleteUser(ctx context.Context, id int) (err error) {
+	ctx, done := r.track(ctx, "delete_user")
+	defer done(&err)
+
+	return r.repo.DeleteUser(ctx, id)
+}
@@ -2,21 +2,69 @@ package validation
 
 import (
 	"errors"
+	"strings"
+	"sync"
+
+	"github.com/nyaruka/phonenumbers"
 )
 
 var (
-	ErrPhoneIsEmpty = errors.New("phone number is empty")
-	ErrInvalidPhone = errors.New("invalid phone number")
+	ErrPhoneIsEmpty  = errors.New("phone number is empty")
+	ErrInvalidPhone  = errors.New("invalid phone number")
+	ErrUnknownRegion = errors.New("unknown phone region")
 )
 
-func ValidatePhone(phone string) error {
-	if phone == "" {
-		return ErrPhoneIsEmpty
-	}
+var (
+	regionMtx     sync.RWMutex
+	defaultRegion = "RU"
+)
 
-	if len(phone) != 11 {
-		return ErrInvalidPhone
+// SetDefaultRegion sets the region, e.g. "RU" or "US", used for numbers
+// written without the international prefix.
+func SetDefaultRegion(region string) error {
+	region = strings.ToUpper(region)
+	if !phonenumbers.GetSupportedRegions()[region] {
+		return ErrUnknownRegion
 	}
 
+	regionMtx.Lock()
+	defer regionMtx.Unlock()
+
+	defaultRegion = region
 	return nil
 }
+
+func DefaultRegion() string {
+	regionMtx.RLock()
+	defer regionMtx.RUnlock()
+
+	return defaultRegion
+}
+
+// NormalizePhone parses the number in the default region and returns it
+// in E.164, e.g. "8 (999) 123-45-67" becomes "+79991234567" in RU.
+func NormalizePhone(phone string) (string, error) {
+	return NormalizePhoneIn(phone, DefaultRegion())
+}
+
+// NormalizePhoneIn is NormalizePhone for the given region.
+func NormalizePhoneIn(phone, region string) (string, error) {
+	if strings.TrimSpace(phone) == "" {
+		return "", ErrPhoneIsEmpty
+	}
+
+	num, err := phonenumbers.Parse(phone, strings.ToUpper(region))
+	if err != nil {
+		return "", ErrInvalidPhone
+	}
+	if !phonenumbers.IsValidNumber(num) {
+		return "", ErrInvalidPhone
+	}
+
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
+
+func ValidatePhone(phone string) error {
+	_, err := NormalizePhone(phone)
+	return err
+}
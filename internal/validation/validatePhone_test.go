@@ -6,40 +6,109 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestValidatePhone(t *testing.T) {
+func TestNormalizePhoneIn(t *testing.T) {
 	testCases := []struct {
 		title       string
 		phone       string
+		region      string
+		expected    string
 		expectedErr string
 	}{
 		{
-			title:       "happy: valid phone",
-			phone:       "13052858783",
-			expectedErr: "",
+			title:    "happy: russian number in E.164",
+			phone:    "+79991234567",
+			region:   "RU",
+			expected: "+79991234567",
+		},
+		{
+			title:    "happy: russian number with leading 8",
+			phone:    "89991234567",
+			region:   "RU",
+			expected: "+79991234567",
+		},
+		{
+			title:    "happy: russian number with spaces, dashes and parentheses",
+			phone:    "8 (999) 123-45-67",
+			region:   "RU",
+			expected: "+79991234567",
+		},
+		{
+			title:    "happy: us number in national format",
+			phone:    "(305) 285-8783",
+			region:   "US",
+			expected: "+13052858783",
+		},
+		{
+			title:    "happy: us number with country code",
+			phone:    "1 305 285 8783",
+			region:   "US",
+			expected: "+13052858783",
+		},
+		{
+			title:    "happy: german number",
+			phone:    "030 901820",
+			region:   "DE",
+			expected: "+4930901820",
+		},
+		{
+			title:    "happy: international number ignores region",
+			phone:    "+44 20 7946 0958",
+			region:   "RU",
+			expected: "+442079460958",
 		},
 		{
 			title:       "sad: empty phone",
-			phone:       "",
+			phone:       "  ",
+			region:      "RU",
 			expectedErr: "phone number is empty",
 		},
 		{
-			title:       "sad: invalid phone",
+			title:       "sad: letters",
+			phone:       "abcdefghijk",
+			region:      "RU",
+			expectedErr: "invalid phone number",
+		},
+		{
+			title:       "sad: too short",
 			phone:       "83746",
+			region:      "RU",
 			expectedErr: "invalid phone number",
 		},
 		{
-			title:       "sad: invalid phone",
+			title:       "sad: too long",
 			phone:       "289374628479356238947563789",
+			region:      "RU",
+			expectedErr: "invalid phone number",
+		},
+		{
+			title:       "sad: us number in russian region",
+			phone:       "13052858783",
+			region:      "RU",
 			expectedErr: "invalid phone number",
 		},
 	}
 
 	for _, tc := range testCases {
-		err := ValidatePhone(tc.phone)
+		phone, err := NormalizePhoneIn(tc.phone, tc.region)
 		if tc.expectedErr != "" {
 			assert.Contains(t, err.Error(), tc.expectedErr, tc.title)
 		} else {
 			assert.Nil(t, err, tc.title)
+			assert.Equal(t, tc.expected, phone, tc.title)
 		}
 	}
 }
+
+func TestSetDefaultRegion(t *testing.T) {
+	defer SetDefaultRegion("RU")
+
+	assert.ErrorIs(t, SetDefaultRegion("XX"), ErrUnknownRegion)
+	assert.Equal(t, "RU", DefaultRegion())
+
+	assert.Nil(t, SetDefaultRegion("us"))
+	assert.Equal(t, "US", DefaultRegion())
+
+	phone, err := NormalizePhone("(305) 285-8783")
+	assert.Nil(t, err)
+	assert.Equal(t, "+13052858783", phone)
+}
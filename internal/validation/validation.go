@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// Violation codes, they tell the client what is wrong with a field.
+const (
+	CodeRequired = "REQUIRED"
+	CodeInvalid  = "INVALID"
+)
+
+// FieldError is a violation of a single field.
+type FieldError struct {
+	Field string
+	Code  string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors holds all violations of a request. errors.Is matches the
+// error of any field.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+
+	return errs
+}
+
+// Validator collects violations instead of stopping at the first one.
+// Only the first violation of a field is kept.
+//
+//	var v validation.Validator
+//	v.Required("login", dto.Login, ErrLoginIsEmpty)
+//	v.Email("email", dto.Email, ErrEmailIsEmpty, ErrInvalidEmail)
+//	v.Phone("phone", &dto.Phone)
+//	return v.Err()
+type Validator struct {
+	errs Errors
+}
+
+// Add records a violation of the field.
+func (v *Validator) Add(field, code string, err error) {
+	if v.Has(field) {
+		return
+	}
+
+	v.errs = append(v.errs, &FieldError{Field: field, Code: code, Err: err})
+}
+
+// Has reports whether the field already has a violation.
+func (v *Validator) Has(field string) bool {
+	for _, fe := range v.errs {
+		if fe.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check records an invalid field when ok is false and returns ok.
+func (v *Validator) Check(ok bool, field string, err error) bool {
+	if !ok {
+		v.Add(field, CodeInvalid, err)
+	}
+
+	return ok
+}
+
+// Required records a missing field when value is empty and returns
+// whether it is set.
+func (v *Validator) Required(field, value string, err error) bool {
+	if value == "" {
+		v.Add(field, CodeRequired, err)
+		return false
+	}
+
+	return true
+}
+
+// Email checks that the field is set and is a valid address.
+func (v *Validator) Email(field, value string, errEmpty, errInvalid error) {
+	if !v.Required(field, value, errEmpty) {
+		return
+	}
+
+	_, err := mail.ParseAddress(value)
+	v.Check(err == nil, field, errInvalid)
+}
+
+// Phone checks the field with NormalizePhone and replaces a valid number
+// with its E.164 form.
+func (v *Validator) Phone(field string, phone *string) {
+	normalized, err := NormalizePhone(*phone)
+	switch {
+	case errors.Is(err, ErrPhoneIsEmpty):
+		v.Add(field, CodeRequired, err)
+	case err != nil:
+		v.Add(field, CodeInvalid, err)
+	default:
+		*phone = normalized
+	}
+}
+
+// Err returns the collected violations, or nil when there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return v.errs
+}
@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errNameIsEmpty  = errors.New("name is empty")
+	errEmailIsEmpty = errors.New("email is empty")
+	errInvalidEmail = errors.New("email is invalid")
+	errInvalidAge   = errors.New("age is invalid")
+)
+
+func TestValidator(t *testing.T) {
+	testCases := []struct {
+		title      string
+		name       string
+		email      string
+		phone      string
+		age        int
+		wantFields map[string]string
+	}{
+		{
+			title: "happy: all fields valid",
+			name:  "Ivan",
+			email: "ivan@example.com",
+			phone: "+79991234567",
+			age:   20,
+		},
+		{
+			title: "sad: all violations are collected",
+			wantFields: map[string]string{
+				"name":  CodeRequired,
+				"email": CodeRequired,
+				"phone": CodeRequired,
+				"age":   CodeInvalid,
+			},
+		},
+		{
+			title: "sad: invalid formats",
+			name:  "Ivan",
+			email: "not an email",
+			phone: "83746",
+			age:   20,
+			wantFields: map[string]string{
+				"email": CodeInvalid,
+				"phone": CodeInvalid,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var v Validator
+		v.Required("name", tc.name, errNameIsEmpty)
+		v.Email("email", tc.email, errEmailIsEmpty, errInvalidEmail)
+		v.Phone("phone", &tc.phone)
+		v.Check(tc.age >= 18, "age", errInvalidAge)
+
+		err := v.Err()
+		if tc.wantFields == nil {
+			assert.Nil(t, err, tc.title)
+			continue
+		}
+
+		var errs Errors
+		assert.True(t, errors.As(err, &errs), tc.title)
+
+		fields := make(map[string]string, len(errs))
+		for _, fe := range errs {
+			fields[fe.Field] = fe.Code
+		}
+		assert.Equal(t, tc.wantFields, fields, tc.title)
+	}
+}
+
+func TestValidatorKeepsFirstViolation(t *testing.T) {
+	var v Validator
+	v.Required("email", "", errEmailIsEmpty)
+	v.Check(false, "email", errInvalidEmail)
+
+	err := v.Err()
+	assert.ErrorIs(t, err, errEmailIsEmpty)
+	assert.NotErrorIs(t, err, errInvalidEmail)
+	assert.Equal(t, "email: email is empty", err.Error())
+}
@@ -0,0 +1,282 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"practice-backend/internal/events"
+	"practice-backend/internal/metrics"
+	"practice-backend/internal/models/webhook"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBackoff      = 10 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultTimeout      = 10 * time.Second
+	DefaultPollInterval = time.Second
+	DefaultRetention    = 7 * 24 * time.Hour
+
+	// batchSize is how many due deliveries are sent at once
+	batchSize = 16
+	userAgent = "practice-backend-webhooks"
+)
+
+type Config struct {
+	// MaxAttempts a delivery gets before it is dead
+	MaxAttempts int
+	// Backoff is the wait before the first retry, it doubles with every
+	// failed attempt up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout of a delivery request
+	Timeout time.Duration
+	// PollInterval is how often due deliveries are looked for
+	PollInterval time.Duration
+	// Retention is how long delivered deliveries stay in the log
+	Retention time.Duration
+	// Data turns the event data into the JSON of the payload, e.g. an
+	// entry into its API representation. The data is sent as is without it.
+	Data    func(e events.Event) any
+	Client  *http.Client
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+}
+
+// payload is the body of a delivery request.
+type payload struct {
+	// ID is the same for all deliveries of an event, receivers drop
+	// repeats by it
+	ID   int         `json:"id"`
+	Type events.Type `json:"type"`
+	Time time.Time   `json:"time"`
+	Data any         `json:"data"`
+}
+
+// Dispatcher turns the events handed over by the relay into deliveries
+// of the webhooks subscribed to them and sends the deliveries, retrying
+// failed ones. Deliveries are kept in the repo, so the pending ones
+// survive restarts.
+type Dispatcher struct {
+	repo webhook.WebhookRepo
+	cfg  Config
+	now  func() time.Time
+}
+
+func NewDispatcher(repo webhook.WebhookRepo, cfg Config) *Dispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	if cfg.Data == nil {
+		cfg.Data = func(e events.Event) any { return e.Data }
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{
+			// a redirect is a failed delivery, the webhook URL has to be fixed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &Dispatcher{
+		repo: repo,
+		cfg:  cfg,
+		now:  func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run sends due deliveries until ctx is done. Attempts in flight are
+// finished first.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	lastPrune := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		d.deliverDue(ctx)
+
+		if now := d.now(); now.Sub(lastPrune) >= time.Hour {
+			lastPrune = now
+			if _, err := d.repo.PruneDeliveries(ctx, now.Add(-d.cfg.Retention)); err != nil {
+				d.cfg.Logger.Error("prune webhook deliveries", "error", err)
+			}
+		}
+	}
+}
+
+// Enqueue creates a delivery of the event for every webhook subscribed
+// to its type. It is an events.Handler: when it fails, the relay hands
+// the event over again and the webhooks queued before get a repeat.
+func (d *Dispatcher) Enqueue(ctx context.Context, e events.Event) error {
+	hooks, err := d.repo.GetWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	for _, hook := range hooks {
+		if !hook.Wants(string(e.Type)) {
+			continue
+		}
+
+		if body == nil {
+			body, err = json.Marshal(payload{ID: e.MessageID, Type: e.Type, Time: e.Time, Data: d.cfg.Data(e)})
+			if err != nil {
+				// a repeat would fail the same way
+				d.cfg.Logger.Error("encode webhook payload", "event_type", e.Type, "error", err)
+				return nil
+			}
+		}
+
+		delivery := webhook.NewDelivery(hook.ID, string(e.Type), body)
+		delivery.CreatedAt = d.now()
+		delivery.NextAttemptAt = delivery.CreatedAt
+		if _, err := d.repo.CreateDelivery(ctx, *delivery); err != nil {
+			return fmt.Errorf("create delivery for webhook %d: %w", hook.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// deliverDue sends the due deliveries, a batch at a time, until none
+// is due or ctx is done.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	// started attempts are not cut by ctx, the request timeout bounds them
+	attemptCtx := context.WithoutCancel(ctx)
+
+	for ctx.Err() == nil {
+		due, err := d.repo.DueDeliveries(ctx, d.now(), batchSize)
+		if err != nil {
+			d.cfg.Logger.Error("get due webhook deliveries", "error", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range due {
+			wg.Go(func() { d.attempt(attemptCtx, delivery) })
+		}
+		wg.Wait()
+	}
+}
+
+// attempt sends the delivery once and records the result: delivered,
+// retried later with backoff, or dead when out of attempts.
+func (d *Dispatcher) attempt(ctx context.Context, delivery webhook.Delivery) {
+	hook, err := d.repo.GetWebhookByID(ctx, delivery.WebhookID)
+	if err != nil {
+		// deleted with its deliveries in the meantime
+		return
+	}
+
+	status, err := d.send(ctx, hook, delivery)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = d.now()
+	delivery.ResponseStatus = status
+
+	log := d.cfg.Logger.With("webhook_id", hook.ID, "delivery_id", delivery.ID, "attempt", delivery.Attempts)
+	switch {
+	case err == nil:
+		delivery.Status = webhook.StatusDelivered
+		delivery.LastError = ""
+		d.cfg.Metrics.IncWebhookAttempt(metrics.WebhookDelivered)
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = webhook.StatusDead
+		delivery.LastError = err.Error()
+		d.cfg.Metrics.IncWebhookAttempt(metrics.WebhookDead)
+		log.Warn("webhook delivery is dead", "error", err)
+	default:
+		delivery.NextAttemptAt = delivery.LastAttemptAt.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		d.cfg.Metrics.IncWebhookAttempt(metrics.WebhookFailed)
+		log.Info("webhook delivery failed", "error", err, "next_attempt_at", delivery.NextAttemptAt)
+	}
+
+	if _, err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Error("update webhook delivery", "error", err)
+	}
+}
+
+// send posts the signed payload and returns the response status code.
+// Any status but 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, hook webhook.Webhook, delivery webhook.Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := d.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the connection is reused when the body is read to the end
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.Backoff
+	for range attempts - 1 {
+		wait *= 2
+		if wait >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+
+	return min(wait, d.cfg.MaxBackoff)
+}
@@ -0,0 +1,196 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"practice-backend/internal/events"
+	"practice-backend/internal/models/webhook"
+	"practice-backend/internal/storage/inmem"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	sentAt := time.Unix(1760000000, 0)
+	body := []byte(`{"type":"entry.created"}`)
+	signature := Sign("secret", sentAt, body)
+
+	testCases := []struct {
+		title     string
+		secret    string
+		timestamp time.Time
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{title: "happy: valid signature", secret: "secret", timestamp: sentAt, body: body, signature: signature, want: true},
+		{title: "sad: wrong secret", secret: "other", timestamp: sentAt, body: body, signature: signature},
+		{title: "sad: changed body", secret: "secret", timestamp: sentAt, body: []byte(`{}`), signature: signature},
+		{title: "sad: replayed with another timestamp", secret: "secret", timestamp: sentAt.Add(time.Second), body: body, signature: signature},
+		{title: "sad: no prefix", secret: "secret", timestamp: sentAt, body: body, signature: signature[len(signaturePrefix):]},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, Verify(tc.secret, tc.timestamp, tc.body, tc.signature), tc.title)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, Config{Backoff: 10 * time.Second, MaxBackoff: time.Minute})
+
+	testCases := []struct {
+		title    string
+		attempts int
+		want     time.Duration
+	}{
+		{title: "happy: first retry", attempts: 1, want: 10 * time.Second},
+		{title: "happy: doubled", attempts: 2, want: 20 * time.Second},
+		{title: "happy: doubled again", attempts: 3, want: 40 * time.Second},
+		{title: "happy: capped", attempts: 4, want: time.Minute},
+		{title: "happy: capped for long", attempts: 50, want: time.Minute},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, d.backoff(tc.attempts), tc.title)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := context.Background()
+
+	var requests atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	hook, err := storage.CreateWebhook(ctx, receiver.URL, []string{string(events.EntryCreated)}, "secret")
+	require.NoError(t, err)
+
+	now := time.Date(2025, 10, 5, 12, 0, 0, 0, time.UTC)
+	d := NewDispatcher(storage, Config{MaxAttempts: 3, Backoff: time.Minute})
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Enqueue(ctx, events.Event{Type: events.EntryStatusChanged, MessageID: 1}))
+	require.NoError(t, d.Enqueue(ctx, events.Event{Type: events.EntryCreated, Time: now, Data: map[string]int{"id": 1}, MessageID: 2}))
+
+	deliveries, err := storage.GetDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1, "only subscribed events are queued")
+	assert.JSONEq(t, `{"id":2,"type":"entry.created","time":"2025-10-05T12:00:00Z","data":{"id":1}}`, string(deliveries[0].Payload))
+
+	steps := []struct {
+		title       string
+		advance     time.Duration
+		wantStatus  string
+		wantAttempt int
+		wantNext    time.Duration
+	}{
+		{title: "first attempt fails", wantStatus: webhook.StatusPending, wantAttempt: 1, wantNext: time.Minute},
+		{title: "not due before the backoff", advance: 30 * time.Second, wantStatus: webhook.StatusPending, wantAttempt: 1, wantNext: 30 * time.Second},
+		{title: "second attempt fails, backoff doubles", advance: 30 * time.Second, wantStatus: webhook.StatusPending, wantAttempt: 2, wantNext: 2 * time.Minute},
+		{title: "third attempt is delivered", advance: 2 * time.Minute, wantStatus: webhook.StatusDelivered, wantAttempt: 3},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		d.deliverDue(ctx)
+
+		delivery, err := storage.GetDeliveryByID(ctx, deliveries[0].ID)
+		require.NoError(t, err, step.title)
+		assert.Equal(t, step.wantStatus, delivery.Status, step.title)
+		assert.Equal(t, step.wantAttempt, delivery.Attempts, step.title)
+		if step.wantStatus == webhook.StatusPending {
+			assert.Equal(t, now.Add(step.wantNext), delivery.NextAttemptAt, step.title)
+			assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus, step.title)
+		}
+	}
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	storage := inmem.NewStorage()
+	ctx := context.Background()
+
+	hook, err := storage.CreateWebhook(ctx, "http://127.0.0.1:1", []string{string(events.EntryCreated)}, "secret")
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	d := NewDispatcher(storage, Config{MaxAttempts: 2, Backoff: time.Minute})
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Enqueue(ctx, events.Event{Type: events.EntryCreated}))
+	d.deliverDue(ctx)
+	now = now.Add(time.Minute)
+	d.deliverDue(ctx)
+
+	deliveries, err := storage.GetDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, webhook.StatusDead, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Zero(t, deliveries[0].ResponseStatus, "no response from a closed port")
+	assert.NotEmpty(t, deliveries[0].LastError)
+
+	now = now.Add(time.Hour)
+	d.deliverDue(ctx)
+	delivery, _ := storage.GetDeliveryByID(ctx, deliveries[0].ID)
+	assert.Equal(t, 2, delivery.Attempts, "dead deliveries are not sent")
+}
+
+func TestDispatcherRun(t *testing.T) {
+	storage := inmem.NewStorage()
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		select {
+		case requests <- received{header: r.Header, body: body}:
+		default:
+		}
+	}))
+	defer receiver.Close()
+
+	hook, err := storage.CreateWebhook(t.Context(), receiver.URL, []string{string(events.EntryStatusChanged)}, "secret")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	d := NewDispatcher(storage, Config{PollInterval: 10 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	wg.Go(func() { assert.NoError(t, d.Run(ctx)) })
+	defer wg.Wait()
+	defer cancel()
+
+	require.NoError(t, d.Enqueue(ctx, events.Event{Type: events.EntryStatusChanged}))
+	deliveries, err := storage.GetDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	select {
+	case r := <-requests:
+		sentAt, err := strconv.ParseInt(r.header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(events.EntryStatusChanged), r.header.Get(EventHeader))
+		assert.Equal(t, "0", r.header.Get(DeliveryHeader))
+		assert.Equal(t, "application/json", r.header.Get("Content-Type"))
+		assert.True(t, Verify("secret", time.Unix(sentAt, 0), r.body, r.header.Get(SignatureHeader)))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the delivery was not sent")
+	}
+}